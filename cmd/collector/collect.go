@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/config"
+	"github.com/RobertKielty/flake-tracker/pkg/httpcache"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+// EXIT_PARTIAL is the exit code of collect --fail-on-partial when some jobs
+// or issues could not be collected
+const EXIT_PARTIAL int = 3
+
+var reportFields log.Fields
+
+// partialErrors lists the jobs and issues that could not be collected, a
+// snapshot of everything else is still saved
+type partialErrors []error
+
+func (e partialErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "Collected partial data\n" + strings.Join(msgs, "\n")
+}
+
+// runCollect collects CI status into a new snapshot in the data directory,
+// from where it can be rendered by report and compared by diff
+func runCollect(args []string) error {
+	fs := flag.NewFlagSet("collect", flag.ExitOnError)
+	cf := addCollectFlags(fs)
+	withReport := fs.Bool("report", false, "Also write the configured reports on the new snapshot")
+	failOnPartial := fs.Bool("fail-on-partial", false,
+		fmt.Sprintf("Exit with code %d, after saving the snapshot, if some jobs or issues could not be collected", EXIT_PARTIAL))
+	fs.Parse(args)
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	loggers := openLoggers(cfg.Logging, startTime)
+	defer loggers.Close()
+	snap, err := collectSnapshot(cfg, &snapshot.Store{Dir: cfg.Store.Path}, loggers, startTime)
+	partial, isPartial := err.(partialErrors)
+	if err != nil && !isPartial {
+		return err
+	}
+	if *withReport {
+		if err = writeReports(cfg.Outputs, snap); err != nil {
+			return err
+		}
+	}
+	if isPartial && *failOnPartial {
+		return &exitError{code: EXIT_PARTIAL, err: partial}
+	}
+	return nil
+}
+
+// collectSnapshot collects the CI status of the dashboards in cfg and the
+// issues linked to them, logging to loggers, saving the snapshot in store. If
+// some jobs or issues could not be collected, including when there is no
+// GitHub token to collect issues with, the snapshot is returned with a
+// partialErrors.
+func collectSnapshot(cfg *config.Config, store *snapshot.Store, loggers *runLoggers, startTime time.Time) (*snapshot.Snapshot, error) {
+	ciStatusLogger, ghLogger := loggers.ciStatus, loggers.gitHub
+
+	if removed, err := cfg.Cache.Prune(startTime); err != nil {
+		log.Warn("Pruning the cache ", err)
+	} else if removed > 0 {
+		log.Infof("Pruned %d cached response(s) older than %s", removed, cfg.Cache.MaxAge)
+	}
+	testGrid, testGridCache := cfg.TestGrid.Source(cfg.Cache)
+	if testGridCache != nil {
+		testGridCache.Logger = ciStatusLogger
+	}
+	collection := ci.NewCollection(cfg.Dashboards, startTime, testGrid, ciStatusLogger)
+	for _, cs := range collection.Dashboards {
+		cs.Workers = cfg.TestGrid.Workers
+	}
+	limiter := &rf.RateLimiter{Cache: cfg.Cache.Store(config.CACHE_GITHUB), Logger: ghLogger}
+	var transport http.RoundTripper = limiter
+	gitHubCache := cfg.Cache.Transport(config.CACHE_GITHUB, limiter)
+	if gitHubCache != nil {
+		gitHubCache.Logger = ghLogger
+		transport = gitHubCache
+	}
+	reportedFlake := &rf.ReportedFlake{Logger: ghLogger}
+	boards, searches := cfg.Boards, cfg.Searches
+	client, clientErr := rf.NewClientFromEnv(context.Background(), transport)
+	if clientErr != nil {
+		// the CI status is still collected, without any issues
+		log.Error("Collecting issues ", clientErr)
+		boards, searches = nil, nil
+	} else {
+		reportedFlake.GitHub = client
+	}
+	err := collectData(collection, reportedFlake, boards, searches) // TODO ciStatus && reportedFlake need to be decoupled
+	for _, u := range limiter.Usage() {
+		log.Infof("GitHub %s quota: %d request(s) used %d, %d not modified, %d of %d left until %s",
+			u.Resource, u.Requests, u.Consumed(), u.NotModified, u.Remaining, u.Limit, u.Reset.Format(time.Kitchen))
+	}
+	logCacheStats(config.CACHE_TESTGRID, testGridCache)
+	logCacheStats(config.CACHE_GITHUB, gitHubCache)
+	if _, partial := err.(partialErrors); err != nil && !partial {
+		return nil, err
+	}
+	if clientErr != nil {
+		partial, _ := err.(partialErrors)
+		err = append(partialErrors{clientErr}, partial...)
+	}
+	snap := snapshot.New(collection, reportedFlake.Issues)
+	id, saveErr := store.Save(snap)
+	if saveErr != nil {
+		log.Error("Saving snapshot ", saveErr)
+	} else {
+		log.Infof("Saved snapshot %s in %s", id, store.Dir)
+	}
+	return snap, err
+}
+
+// collectData collects CI status for c and the issues linked to it. Jobs whose
+// tests could not be retrieved, reported as unknown, boards and searches that
+// could not be read and cards whose issues could not be collected are logged
+// and returned in a partialErrors.
+func collectData(c *ci.Collection, reportedFlake *rf.ReportedFlake, boards []config.Board, searches []string) error {
+	log.SetFormatter(&log.TextFormatter{})
+	for _, cs := range c.Dashboards {
+		reportFields = log.Fields{
+			"DATA BEING RETRIEVED": "Job Status Summary TestGrid TabGroup",
+			"TEST_GRID TAB_GROUP":  cs.Name,
+			"COLLECTION TIME":      cs.CollectedAt,
+			"TB GRP SMMRY URL":     ci.SummaryUrl(cs.Name),
+		}
+		log.WithFields(reportFields).Info("Collecting")
+	}
+
+	var partial partialErrors
+	if err := c.Collect(); err != nil {
+		log.Error("Collecting CI status ", err)
+		if _, ok := err.(ci.JobErrors); !ok {
+			return err
+		}
+		partial = append(partial, err)
+	}
+
+	var readers []rf.BoardReader
+	for _, board := range boards {
+		readers = append(readers, board.Reader())
+	}
+	for _, query := range searches {
+		readers = append(readers, &rf.IssueSearch{Query: query})
+	}
+	for _, reader := range readers {
+		reportedFlake.Board = reader
+		if err := reportedFlake.CollectIssuesFromBoard(c); err != nil {
+			log.Error("Collecting issues ", err)
+			partial = append(partial, err)
+		}
+	}
+
+	if len(partial) > 0 {
+		return partial
+	}
+	return nil
+}
+
+// logCacheStats logs the hits and misses of the cache of source, if any
+func logCacheStats(source string, cache *httpcache.Transport) {
+	if cache != nil {
+		log.Infof("%s cache: %s", source, cache.Stats())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/RobertKielty/flake-tracker/pkg/diff"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// runDiff writes the changes between two stored snapshots to stdout,
+// defaulting to the two most recent snapshots
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	from := fs.String("from", "", "ID of the older snapshot to diff, defaults to the one before --to")
+	to := fs.String("to", "", "ID of the newer snapshot to diff, defaults to the latest")
+	fs.Parse(args)
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	store := &snapshot.Store{Dir: cfg.Store.Path}
+
+	ids, err := store.List()
+	if err != nil {
+		return err
+	}
+	if *to == "" && len(ids) > 0 {
+		*to = ids[len(ids)-1]
+	}
+	if *from == "" {
+		for _, id := range ids {
+			if id < *to {
+				*from = id
+			}
+		}
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("Need two snapshots to diff, found %d in %s", len(ids), store.Dir)
+	}
+
+	old, err := store.Load(*from)
+	if err != nil {
+		return err
+	}
+	cur, err := store.Load(*to)
+	if err != nil {
+		return err
+	}
+	return diff.Compare(old, cur).Write(os.Stdout)
+}
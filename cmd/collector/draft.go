@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/RobertKielty/flake-tracker/pkg/draft"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+const DEFAULT_ISSUE_REPO string = "kubernetes/kubernetes"
+
+// runDraftIssues drafts a flake issue for each untracked flaking or failing
+// test in a snapshot, writing them to files unless --file is given
+func runDraftIssues(args []string) error {
+	fs := flag.NewFlagSet("draft-issues", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	id := fs.String("snapshot", LATEST_SNAPSHOT, "ID of the snapshot to draft issues from")
+	dir := fs.String("dir", "drafts", "Directory the drafts are written to")
+	limit := fs.Int("limit", 10, "Maximum number of issues drafted, the most severe flakes are drafted first, 0 for no limit")
+	file := fs.Bool("file", false, "File the drafts as issues on GitHub instead of writing them to --dir")
+	repo := fs.String("repo", DEFAULT_ISSUE_REPO, "owner/name of the repository --file files issues on")
+	fs.Parse(args)
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+
+	snap, err := loadSnapshot(cfg, *id)
+	if err != nil {
+		return err
+	}
+	drafts := draft.Untracked(snap)
+	if *limit > 0 && len(drafts) > *limit {
+		drafts = drafts[:*limit]
+	}
+
+	if !*file {
+		paths, err := draft.Write(*dir, drafts)
+		for _, p := range paths {
+			fmt.Println(p)
+		}
+		return err
+	}
+
+	ownerName := strings.Split(*repo, "/")
+	if len(ownerName) != 2 {
+		return fmt.Errorf("--repo %q is not of the form owner/name", *repo)
+	}
+	ctx := context.Background()
+	client, err := rf.NewClientFromEnv(ctx, nil)
+	if err != nil {
+		return err
+	}
+	filer := &draft.Filer{Client: client.Client, Owner: ownerName[0], Repo: ownerName[1]}
+	for _, d := range drafts {
+		u, err := filer.File(ctx, d)
+		if err != nil {
+			return fmt.Errorf("Filing %q %v", d.Title, err)
+		}
+		fmt.Println(u)
+	}
+	return nil
+}
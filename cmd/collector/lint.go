@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
+)
+
+// runLintIssues checks each issue body file named in args, or stdin if there
+// are none, against the flake issue template and prints what to fix
+func runLintIssues(args []string) error {
+	fs := flag.NewFlagSet("lint-issues", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	labels := fs.String("labels", "", "Comma separated labels of the issue(s), e.g. sig/node,kind/flake")
+	id := fs.String("snapshot", "",
+		"ID of the snapshot, or "+LATEST_SNAPSHOT+", used to check that the tests named are on the linked job")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lint-issues [flags] [ISSUE_BODY_FILE...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	linter := &lint.Linter{}
+	if *id != "" {
+		cfg, err := cf.load()
+		if err != nil {
+			return err
+		}
+		snap, err := loadSnapshot(cfg, *id)
+		if err != nil {
+			return err
+		}
+		linter.JobTests = jobTests(snap.Collection)
+	}
+
+	var issueLabels []string
+	if *labels != "" {
+		issueLabels = strings.Split(*labels, ",")
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	failed := 0
+	for _, name := range files {
+		var body []byte
+		var err error
+		if name == "-" {
+			body, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			body, err = ioutil.ReadFile(name)
+		}
+		if err != nil {
+			return err
+		}
+
+		findings := linter.Lint(lint.Issue{Body: string(body), Labels: issueLabels})
+		if lint.HasErrors(findings) {
+			failed++
+		}
+		if len(findings) == 0 {
+			fmt.Printf("%s: OK\n", name)
+		}
+		for _, f := range findings {
+			fmt.Printf("%s: %s %s: %s\n    fix: %s\n", name, f.Severity, f.Rule, f.Message, f.Fix)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d issue(s) do not follow the flake template", failed, len(files))
+	}
+	return nil
+}
+
+// jobTests looks up the tests of a job on a dashboard of c, the job's test
+// table is only collected for flaky and failing jobs
+func jobTests(c *ci.Collection) func(dashboard, job string) ([]string, bool) {
+	return func(dashboard, job string) ([]string, bool) {
+		j, found := c.Job(job)
+		if !found || !onDashboard(j, dashboard) {
+			return nil, false
+		}
+		var tests []string
+		if j.JobTestResults != nil {
+			for _, t := range j.JobTestResults.Tests {
+				tests = append(tests, t.Name)
+			}
+		}
+		return tests, true
+	}
+}
+
+// onDashboard returns true if j was collected from dashboard
+func onDashboard(j ci.JobStatus, dashboard string) bool {
+	if j.Dashboard == dashboard {
+		return true
+	}
+	for _, d := range j.AlsoOn {
+		if d == dashboard {
+			return true
+		}
+	}
+	return false
+}
@@ -1,150 +1,231 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
-	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/config"
+	"github.com/RobertKielty/flake-tracker/pkg/report"
 	log "github.com/sirupsen/logrus"
 )
 
-// TODO Create a Flake Issue linter module for use by this and Prow Robot
-// TODO Create a Presenter package
-// TODO Replace TestGrid scraping with BigTable Queries
+// TODO Add a BigTable backed ci.Source to replace TestGrid scraping
 
-var (
-	reportFields log.Fields
-)
+// command is a subcommand of the collector, run with the arguments that
+// follow its name
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-func collectData(cs *ci.CiStatus, rf *rf.ReportedFlake) {
-	log.SetFormatter(&log.TextFormatter{})
-	reportFields = log.Fields{
-		"DATA BEING RETRIEVED": "Job Status Summary TestGrid TabGroup",
-		"TEST_GRID TAB_GROUP":  cs.Name,
-		"COLLECTION TIME":      cs.CollectedAt,
-		"TB GRP SMMRY URL":     cs.TabGroupSummaryUrl,
-	}
+var commands = []command{
+	{"collect", "Collect CI status and linked issues into a snapshot", runCollect},
+	{"report", "Render a snapshot in one or more output formats", runReport},
+	{"diff", "Report the changes between two snapshots", runDiff},
+	{"lint-issues", "Check flake issue bodies against the flake issue template", runLintIssues},
+	{"draft-issues", "Draft flake issues for the flakes no issue tracks", runDraftIssues},
+	{"serve", "Serve an HTML report that is re-collected on a schedule", runServe},
+	{"version", "Print the version of the collector", runVersion},
+}
 
-	cs.CollectStatus()
-	cs.CollectFlakyTests()
-	cs.CollectFailedTests()
-	rf.CollectIssuesFromBoard(cs)
-}
-
-// runs a report against on the jobs in tab group status summary for jobs
-// that are flaking, failed and passing
-func runReport(cs *ci.CiStatus) {
-
-	reportStartTime := cs.CollectedAt.Format(time.UnixDate)
-	flakeCount := 0
-	failCount := 0
-	passingCount := 0
-
-	for jobName, job := range cs.FlakingJobs {
-		flakeCount++
-		results := job.JobTestResults
-		for i, flakyTest := range results.Tests {
-			// jobOwner,
-			if len(flakyTest.LinkedBugs) > 0 {
-				for _, reportedBy := range flakyTest.LinkedBugs {
-					fmt.Printf(`%s,%s,%s,"%d of %d","%s","%s","%s","%T - %v"`+"\n",
-						reportStartTime,
-						job.OverallStatus,
-						jobName,
-						i+1,
-						len(results.Tests),
-						flakyTest.Name,
-						job.Url,
-						flakyTest.Sig,
-						reportedBy,
-						reportedBy)
-				}
-			} else {
-				fmt.Printf(`%s,%s,%s,"%d of %d","%s","%s","%s"`+"\n",
-					reportStartTime,
-					job.OverallStatus,
-					jobName,
-					i+1,
-					len(results.Tests),
-					flakyTest.Name,
-					job.Url,
-					flakyTest.Sig)
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			err := c.run(os.Args[2:])
+			if ee, ok := err.(*exitError); ok {
+				log.Error(ee.err)
+				os.Exit(ee.code)
+			}
+			if err != nil {
+				log.Fatal(err)
 			}
+			return
 		}
 	}
+	if name != "help" && name != "-h" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+// exitError is returned by a command that should exit with code, rather
+// than 1, after logging err
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> --help' for the flags of a command\n", filepath.Base(os.Args[0]))
+}
+
+// configFlags are the flags shared by the commands that read a config file,
+// each overrides the matching value of the file
+type configFlags struct {
+	fs           *flag.FlagSet
+	file         *string
+	tabGroups    *string
+	projectBoard *int64 // projectBoard to refresh are nil unless added by addCollectFlags
+	workers      *int
+	record       *string
+	replay       *string
+	refresh      *bool
+	dataDir      *string
+	output       *string
+}
 
-	for jobName, jobStatus := range cs.FailedJobs {
-		failCount++
-		jobFailedTests := jobStatus.JobTestResults
-		for _, failedTest := range jobFailedTests.Tests {
-			fmt.Printf("%s,%s,%s,\"%s\",\"%s\",%s\n",
-				cs.CollectedAt.Format(time.UnixDate),
-				jobStatus.OverallStatus, jobName, failedTest.Sig,
-				failedTest.Name, jobStatus.Url)
+// addConfigFlags registers the config flags on fs
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	defaults := config.Default()
+	return &configFlags{
+		fs: fs,
+		file: fs.String("config", "",
+			"YAML file configuring dashboards, project boards, outputs, data store and logging, flags override its values"),
+		tabGroups: fs.String("tab-group", strings.Join(defaults.Dashboards, ","),
+			"Comma separated list of TestGrid TabGroups (dashboards) to report on"),
+		dataDir: fs.String("data-dir", defaults.Store.Path,
+			"Directory snapshots of CI status and linked issues are saved in"),
+		output: fs.String("output", defaults.Outputs[0].Format,
+			"Output format of the report written to stdout, one of "+strings.Join(report.Formats, ", ")),
+	}
+}
+
+// addCollectFlags registers the config flags on fs of the commands that
+// collect CI status and issues
+func addCollectFlags(fs *flag.FlagSet) *configFlags {
+	defaults := config.Default()
+	f := addConfigFlags(fs)
+	f.projectBoard = fs.Int64("project-board", defaults.Boards[0].ID,
+		"ID of the GitHub project board flake issues are tracked on")
+	f.workers = fs.Int("workers", defaults.TestGrid.Workers,
+		"Number of job test tables fetched from TestGrid concurrently")
+	f.record = fs.String("record", defaults.TestGrid.Record,
+		"Directory every TestGrid response is saved to as a fixture")
+	f.replay = fs.String("replay", defaults.TestGrid.Replay,
+		"Directory of fixtures saved by --record to serve TestGrid responses from")
+	f.refresh = fs.Bool("refresh", false,
+		"Fetch every TestGrid and GitHub response rather than reusing cached ones")
+	return f
+}
+
+// load reads --config, or the defaults if it is not set, and overrides its
+// values with any config flags given on the command line
+func (f *configFlags) load() (*config.Config, error) {
+	cfg := config.Default()
+	if *f.file != "" {
+		var err error
+		if cfg, err = config.Load(*f.file); err != nil {
+			return nil, err
 		}
 	}
 
-	for jobName, jobStatus := range cs.PassingJobs {
-		passingCount++
-		fmt.Printf("%s,%s,%s,\"%s\",\"%s\",%s\n",
-			cs.CollectedAt.Format(time.UnixDate),
-			jobStatus.OverallStatus, jobName, "", "", jobStatus.Url)
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "tab-group":
+			cfg.Dashboards = splitList(*f.tabGroups)
+		case "project-board":
+			cfg.Boards = []config.Board{{ID: *f.projectBoard}}
+		case "workers":
+			cfg.TestGrid.Workers = *f.workers
+		case "record":
+			cfg.TestGrid.Record = *f.record
+		case "replay":
+			cfg.TestGrid.Replay = *f.replay
+		case "refresh":
+			cfg.Cache.Refresh = *f.refresh
+		case "data-dir":
+			cfg.Store.Path = *f.dataDir
+		case "output":
+			cfg.Outputs = []config.Output{{Format: *f.output, Path: config.STDOUT}}
+		}
+	})
+	return cfg, cfg.Validate()
+}
+
+// splitList returns the entries of a comma separated list, trimmed of spaces,
+// leaving out empty ones
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
 	}
-	totalCount := flakeCount + failCount + passingCount
+	return entries
+}
 
-	// TODO Summary report
-	// Overview, Percentages and Status (RYG) calculation
-	fmt.Printf("\"%s\",Total/Failing/Flaking/Passing, %d,%d,%d,%d\n",
-		reportStartTime, totalCount, failCount, flakeCount, passingCount)
-	fmt.Printf("\"%s\", Percentage Failing/Flaking/Passing, %2.1f ,%2.1f ,%2.1f\n",
-		reportStartTime,
-		float64(failCount)/float64(totalCount)*100,
-		float64(flakeCount)/(float64(totalCount))*100,
-		float64(passingCount)/float64(totalCount)*100)
+// runLoggers are the loggers of the collections made by a command, each
+// writing to its own log file
+type runLoggers struct {
+	ciStatus *log.Logger
+	gitHub   *log.Logger
+	files    []*os.File
+}
 
+// openLoggers opens the log files of the collections made by a command
+// started at startTime, they stay open until Close
+func openLoggers(cfg config.Logging, startTime time.Time) *runLoggers {
+	l := &runLoggers{}
+	l.ciStatus = l.open(cfg, "ci-status", startTime)
+	l.gitHub = l.open(cfg, "gh-logger", startTime)
+	return l
 }
 
-func main() {
-	var startTime = time.Now()
-	// TODO this is messed up!
-	var ciStatusLogger = setUpLogging("ci-status", startTime)
-	var ghLogger = setUpLogging("gh-logger", startTime)
-
-	tgBlocking := &ci.CiStatus{
-		Name:        "sig-release-master-informing",
-		CollectedAt: startTime,
-		Logger:      ciStatusLogger,
+func (l *runLoggers) open(cfg config.Logging, name string, startTime time.Time) *log.Logger {
+	logger, file := setUpLogging(cfg, name, startTime)
+	if file != nil {
+		l.files = append(l.files, file)
 	}
-	reportedFlake := &rf.ReportedFlake{
-		Logger:   ghLogger,
-		CiStatus: tgBlocking,
+	return logger
+}
+
+// Close closes the log files
+func (l *runLoggers) Close() {
+	for _, f := range l.files {
+		f.Close()
 	}
-	collectData(tgBlocking, reportedFlake) // TODO ciStatus && reportedFlake need to be decoupled
-	runReport(tgBlocking)                  // TODO extract runReport to a reporter class parameterised on format
-	tgBlocking.Logger.Writer().Close()
 }
 
-func setUpLogging(name string, startTime time.Time) *log.Logger {
+// setUpLogging returns a logger writing to a log file for name, along with
+// the file, which is nil if the logger falls back to stderr
+func setUpLogging(cfg config.Logging, name string, startTime time.Time) (*log.Logger, *os.File) {
 
 	var (
-		formattedTime = startTime.Format("Jan-02-2006")
-		logFilename   = fmt.Sprintf("%s-%s.log", name, formattedTime)
+		formattedTime = startTime.Format(cfg.DateFormat)
+		logFilename   = filepath.Join(cfg.Dir, fmt.Sprintf("%s-%s.log", name, formattedTime))
 		logger        = log.New()
 	)
 
 	logger.SetFormatter(&log.JSONFormatter{})
-	logger.SetLevel(log.TraceLevel)
+	level, _ := log.ParseLevel(cfg.Level) // checked by config.Validate
+	logger.SetLevel(level)
 
 	// For now, one human readable log file with datetime stamp per run
 	file, err := os.OpenFile(logFilename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 
-	if err == nil {
-		logger.Out = file
-	} else {
+	if err != nil {
 		logger.Error("Failed to log to file, using default stderr", err)
+		return logger, nil
 	}
-
-	return logger
+	logger.Out = file
+	return logger, file
 }
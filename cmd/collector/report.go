@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/RobertKielty/flake-tracker/pkg/config"
+	"github.com/RobertKielty/flake-tracker/pkg/report"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+const LATEST_SNAPSHOT string = "latest"
+
+// runReport renders a stored snapshot, the latest by default, to each of the
+// configured outputs
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	id := fs.String("snapshot", LATEST_SNAPSHOT, "ID of the snapshot to report on")
+	fs.Parse(args)
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+
+	snap, err := loadSnapshot(cfg, *id)
+	if err != nil {
+		return err
+	}
+	return writeReports(cfg.Outputs, snap)
+}
+
+// loadSnapshot loads the snapshot with id from the store in cfg, or the
+// latest if id is empty or LATEST_SNAPSHOT
+func loadSnapshot(cfg *config.Config, id string) (*snapshot.Snapshot, error) {
+	store := &snapshot.Store{Dir: cfg.Store.Path}
+	if id == "" || id == LATEST_SNAPSHOT {
+		return store.Latest()
+	}
+	return store.Load(id)
+}
+
+// writeReports writes a report on snap to each output, carrying on past any
+// output that fails
+func writeReports(outputs []config.Output, snap *snapshot.Snapshot) error {
+	var firstErr error
+	for _, o := range outputs {
+		if err := writeReport(o, snap); err != nil {
+			log.Errorf("Writing %s report to %s %v", o.Format, o.Path, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// writeReport writes a report on snap in the format and to the path of o
+func writeReport(o config.Output, snap *snapshot.Snapshot) error {
+	reporter, err := report.New(o.Format)
+	if err != nil {
+		return err
+	}
+	if o.Path == "" || o.Path == config.STDOUT {
+		return reporter.Report(os.Stdout, snap)
+	}
+
+	f, err := os.Create(o.Path)
+	if err != nil {
+		return err
+	}
+	if err = reporter.Report(f, snap); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"time"
+
+	"github.com/RobertKielty/flake-tracker/pkg/server"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+// runServe serves an HTML version of the report, re-collecting it on a
+// schedule, until the server fails
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	cf := addCollectFlags(fs)
+	port := fs.Int("port", 8080, "Port the HTML report and JSON API are served on")
+	interval := fs.Duration("interval", server.DEFAULT_INTERVAL, "How often the report is re-collected")
+	fs.Parse(args)
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	store := &snapshot.Store{Dir: cfg.Store.Path}
+	loggers := openLoggers(cfg.Logging, time.Now()) // shared by every collection
+	defer loggers.Close()
+
+	srv := &server.Server{
+		Collect: func() (*snapshot.Snapshot, error) {
+			snap, err := collectSnapshot(cfg, store, loggers, time.Now())
+			if _, partial := err.(partialErrors); partial {
+				return snap, nil // logged as it was collected, serve what was collected
+			}
+			return snap, err
+		},
+		Interval: *interval,
+		Logger:   log.StandardLogger(),
+	}
+	return srv.ListenAndServe(":" + strconv.Itoa(*port))
+}
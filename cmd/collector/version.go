@@ -0,0 +1,12 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/RobertKielty/flake-tracker/pkg/version"
+)
+
+func runVersion(args []string) error {
+	fmt.Println(version.Version)
+	return nil
+}
@@ -7,4 +7,5 @@ require (
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.7.0
 	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+	gopkg.in/yaml.v2 v2.4.0
 )
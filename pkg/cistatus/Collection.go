@@ -0,0 +1,146 @@
+package cistatus
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Collection is the combined CI status of several TestGrid dashboards
+// collected in a single run
+type Collection struct {
+	CollectedAt time.Time
+	Dashboards  []*CiStatus
+}
+
+// NewCollection returns a Collection of the named dashboards, each collected
+// from src and logged to logger
+func NewCollection(names []string, collectedAt time.Time, src Source, logger *log.Logger) *Collection {
+	c := &Collection{CollectedAt: collectedAt}
+	for _, name := range names {
+		c.Dashboards = append(c.Dashboards, &CiStatus{
+			Name:        name,
+			CollectedAt: collectedAt,
+			Logger:      logger,
+			Source:      src,
+		})
+	}
+	return c
+}
+
+// Collect populates each dashboard in c with its job status summary and the
+// tests of its flaking and failed jobs. A job that appears on several
+// dashboards is kept on the dashboard it has its most severe status on, the
+// first of them if there is a tie, with the others listed in its AlsoOn
+// field, so that it is only fetched and reported once. Count on each
+// dashboard excludes jobs that were kept on another one.
+// Jobs whose tests could not be retrieved do not stop the collection, they
+// are returned together in a JobErrors once every dashboard has been collected.
+func (c *Collection) Collect() error {
+	for _, cs := range c.Dashboards {
+		if err := cs.CollectStatus(); err != nil {
+			return err
+		}
+	}
+
+	c.dedupe()
+
+	errs := make(JobErrors)
+	for _, cs := range c.Dashboards {
+		for _, collect := range []func() error{cs.CollectFlakyTests, cs.CollectFailedTests} {
+			err := collect()
+			if jobErrs, ok := err.(JobErrors); ok {
+				for jobName, jobErr := range jobErrs {
+					errs[jobName] = jobErr
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Statuses returns the statuses of the jobs on all dashboards in c in
+// reporting order, see KnownStatuses
+func (c *Collection) Statuses() []OverallStatus {
+	seen := make(map[OverallStatus]bool)
+	var statuses []OverallStatus
+	for _, cs := range c.Dashboards {
+		for status := range cs.Jobs {
+			if !seen[status] {
+				seen[status] = true
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	sortStatuses(statuses)
+	return statuses
+}
+
+// Job looks up a job by name across all dashboards in c
+func (c *Collection) Job(name string) (JobStatus, bool) {
+	for _, cs := range c.Dashboards {
+		for _, jobs := range cs.Jobs {
+			if job, exists := jobs[name]; exists {
+				return job, true
+			}
+		}
+	}
+	return JobStatus{}, false
+}
+
+// dedupe keeps each job on the dashboard it has its most severe status on,
+// removing it from the others and recording them in the job's AlsoOn.
+// Statuses left with no jobs are removed.
+func (c *Collection) dedupe() {
+	// kept maps a job name to the dashboard it is kept on
+	kept := make(map[string]*CiStatus)
+	for _, cs := range c.Dashboards {
+		for status, jobs := range cs.Jobs {
+			for name := range jobs {
+				if k, exists := kept[name]; !exists || status.rank() < statusOf(k, name).rank() {
+					kept[name] = cs
+				}
+			}
+		}
+	}
+
+	alsoOn := make(map[string][]string)
+	for _, cs := range c.Dashboards {
+		for status, jobs := range cs.Jobs {
+			for name := range jobs {
+				if kept[name] == cs {
+					continue
+				}
+				alsoOn[name] = append(alsoOn[name], cs.Name)
+				delete(jobs, name)
+				cs.Count--
+			}
+			if len(jobs) == 0 {
+				delete(cs.Jobs, status)
+			}
+		}
+	}
+	for name, dashboards := range alsoOn {
+		cs := kept[name]
+		jobs := cs.Jobs[statusOf(cs, name)]
+		job := jobs[name]
+		job.AlsoOn = append(job.AlsoOn, dashboards...)
+		jobs[name] = job
+	}
+}
+
+// statusOf returns the status of the job name on cs
+func statusOf(cs *CiStatus, name string) OverallStatus {
+	for status, jobs := range cs.Jobs {
+		if _, exists := jobs[name]; exists {
+			return status
+		}
+	}
+	return ""
+}
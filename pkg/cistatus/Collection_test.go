@@ -0,0 +1,61 @@
+package cistatus
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Tests that a job shown on several dashboards is collected once, on the
+// first dashboard, with the other dashboards recorded against it
+func TestCollectionDedupesJobs(t *testing.T) {
+	src := &fixtureSource{
+		jobs: map[string]JobStatus{
+			"shared-job": {OverallStatus: "FLAKY"},
+		},
+		tables: map[string]*TestGridJobResult{"shared-job": {}},
+	}
+	c := NewCollection([]string{"blocking", "informing"}, time.Now(), src, log.New())
+
+	if err := c.Collect(); err != nil {
+		t.Fatalf("Collect returned %v", err)
+	}
+	blocking, informing := c.Dashboards[0], c.Dashboards[1]
+	if len(informing.Jobs[FLAKY]) != 0 || informing.Count != 0 {
+		t.Errorf("Expected shared-job to be removed from informing, found %v", informing.Jobs[FLAKY])
+	}
+	job, exists := blocking.Jobs[FLAKY]["shared-job"]
+	if !exists {
+		t.Fatalf("Expected shared-job on blocking")
+	}
+	if job.Dashboard != "blocking" || len(job.AlsoOn) != 1 || job.AlsoOn[0] != "informing" {
+		t.Errorf("Unexpected dashboards for shared-job %s %v", job.Dashboard, job.AlsoOn)
+	}
+}
+
+// Tests that a job with different statuses on several dashboards is kept on
+// the dashboard it is most severe on, and that statuses left without jobs
+// are removed
+func TestCollectionDedupesToMostSevere(t *testing.T) {
+	blocking := &CiStatus{Name: "blocking", Count: 1, Jobs: map[OverallStatus]map[string]JobStatus{
+		FLAKY: {"shared-job": {OverallStatus: FLAKY, Dashboard: "blocking"}},
+	}}
+	informing := &CiStatus{Name: "informing", Count: 2, Jobs: map[OverallStatus]map[string]JobStatus{
+		FAILING: {"shared-job": {OverallStatus: FAILING, Dashboard: "informing"}},
+		PASSING: {"other-job": {OverallStatus: PASSING, Dashboard: "informing"}},
+	}}
+	c := &Collection{Dashboards: []*CiStatus{blocking, informing}}
+	c.dedupe()
+
+	if _, exists := blocking.Jobs[FLAKY]; exists || blocking.Count != 0 {
+		t.Errorf("Expected shared-job and its status to be removed from blocking, found %v", blocking.Jobs)
+	}
+	job, exists := informing.Jobs[FAILING]["shared-job"]
+	if !exists || len(job.AlsoOn) != 1 || job.AlsoOn[0] != "blocking" || informing.Count != 2 {
+		t.Errorf("Expected shared-job to be kept failing on informing, got %+v", informing.Jobs)
+	}
+	if statuses := c.Statuses(); len(statuses) != 2 || statuses[0] != FAILING || statuses[1] != PASSING {
+		t.Errorf("Expected only statuses with jobs, got %v", statuses)
+	}
+}
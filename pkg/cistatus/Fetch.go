@@ -0,0 +1,105 @@
+package cistatus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_HTTP_TIMEOUT time.Duration = 30 * time.Second
+)
+
+var (
+	// httpClient is shared by every TestGrid request made by the package
+	httpClient = &http.Client{Timeout: DEFAULT_HTTP_TIMEOUT}
+
+	// DefaultRetryPolicy is used by a TestGrid source with no RetryPolicy
+	DefaultRetryPolicy = RetryPolicy{
+		Attempts:   4,
+		Backoff:    time.Second,
+		MaxBackoff: 16 * time.Second,
+	}
+)
+
+// RetryPolicy controls how a failed HTTP GET is retried. Requests that fail
+// to connect, time out, are rate limited (429) or hit a server error (5xx)
+// are retried, waiting Backoff before the first retry and doubling the wait
+// up to MaxBackoff after each one. A Retry-After header overrides the wait,
+// which is still capped at MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// FetchError is returned when url could not be retrieved, Status is zero if
+// no response was received
+type FetchError struct {
+	URL      string
+	Status   int
+	Attempts int
+	Err      error
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Fetching %s failed after %d attempt(s): %v", e.URL, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("Fetching %s failed after %d attempt(s): HTTP status %d %s",
+		e.URL, e.Attempts, e.Status, http.StatusText(e.Status))
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// get returns the body of a successful GET on url, retrying as per p
+func (p RetryPolicy) get(client *http.Client, url string) ([]byte, error) {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		body, status, wait, err := getOnce(client, url)
+		if err == nil && status == http.StatusOK {
+			return body, nil
+		}
+
+		retryable := err != nil || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || attempt >= p.Attempts {
+			return nil, &FetchError{URL: url, Status: status, Attempts: attempt, Err: err}
+		}
+
+		if wait == 0 {
+			wait = backoff
+		} else if wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+		time.Sleep(wait)
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// getOnce makes a single GET on url, returning the body, status code and the
+// wait requested by any Retry-After header in the response
+func getOnce(client *http.Client, url string) ([]byte, int, time.Duration, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		wait = time.Duration(secs) * time.Second
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, wait, err
+	}
+	return body, resp.StatusCode, wait, nil
+}
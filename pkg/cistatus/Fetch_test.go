@@ -0,0 +1,95 @@
+package cistatus
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// Tests that server errors are retried until TestGrid responds
+func TestGetRetriesServerErrors(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"a-job":{"overall_status":"FLAKY"}}`))
+	}))
+	defer ts.Close()
+
+	tg := &TestGrid{Client: ts.Client(), Retry: &testRetryPolicy}
+	var jobs map[string]JobStatus
+	if err := tg.getJSON(ts.URL, &jobs); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if calls != 3 || jobs["a-job"].OverallStatus != "FLAKY" {
+		t.Errorf("Unexpected result after %d calls: %v", calls, jobs)
+	}
+}
+
+// Tests that client errors are not retried and are returned as a FetchError
+func TestGetReturnsFetchError(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	tg := &TestGrid{Client: ts.Client(), Retry: &testRetryPolicy}
+	err := tg.getJSON(ts.URL, &map[string]JobStatus{})
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) {
+		t.Fatalf("Expected a FetchError, got %T %v", err, err)
+	}
+	if fetchErr.Status != http.StatusNotFound || fetchErr.Attempts != 1 || calls != 1 {
+		t.Errorf("Unexpected FetchError %+v after %d calls", fetchErr, calls)
+	}
+}
+
+// Tests that malformed data is not reported as a FetchError
+func TestGetMalformedData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>not json</html>`))
+	}))
+	defer ts.Close()
+
+	tg := &TestGrid{Client: ts.Client(), Retry: &testRetryPolicy}
+	err := tg.getJSON(ts.URL, &map[string]JobStatus{})
+	var fetchErr *FetchError
+	if err == nil || errors.As(err, &fetchErr) {
+		t.Errorf("Expected an unmarshalling error, got %v", err)
+	}
+}
+
+// Tests that the wait asked for by a Retry-After header is capped by the
+// policy's MaxBackoff
+func TestGetCapsRetryAfter(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	tg := &TestGrid{Client: ts.Client(), Retry: &testRetryPolicy}
+	start := time.Now()
+	var jobs map[string]JobStatus
+	if err := tg.getJSON(ts.URL, &jobs); err != nil {
+		t.Fatalf("Expected success after a retry, got %v", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Expected Retry-After to be capped at %s, waited %s", testRetryPolicy.MaxBackoff, waited)
+	}
+}
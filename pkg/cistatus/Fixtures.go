@@ -0,0 +1,112 @@
+package cistatus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const (
+	FIXTURE_EXT string = ".json"
+)
+
+// FixturePath returns the file in dir the response to a GET on u is recorded
+// in, named after its host and path, e.g.
+// testgrid.k8s.io/sig-release-master-blocking/summary.json. A query, which
+// can name a job of any length, is hashed, as in httpcache.Store, so the
+// name stays short enough for the file system.
+func FixturePath(dir string, u *url.URL) string {
+	name := filepath.Join(dir, u.Host, filepath.FromSlash(u.Path))
+	if u.RawQuery != "" {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		name += "_" + hex.EncodeToString(sum[:])
+	}
+	return name + FIXTURE_EXT
+}
+
+// Recorder is an http.RoundTripper that saves the body of every successful
+// response to its fixture in Dir, see FixturePath, so that it can be replayed
+// by a Replayer
+type Recorder struct {
+	Dir       string
+	Transport http.RoundTripper // http.DefaultTransport when nil
+}
+
+// RoundTrip makes the request with r.Transport, recording the response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	path := FixturePath(r.Dir, req.URL)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(path, body, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that answers GETs with the fixtures
+// recorded in Dir by a Recorder. Requests without a fixture are not found.
+type Replayer struct {
+	Dir string
+}
+
+// RoundTrip returns the fixture recorded for req
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	status := http.StatusOK
+	path := FixturePath(r.Dir, req.URL)
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		status = http.StatusNotFound
+		body = []byte(fmt.Sprintf("No fixture %s recorded for %s", path, req.URL))
+	} else if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// NewRecordingTestGrid returns a TestGrid source that records the responses
+// it fetches in dir
+func NewRecordingTestGrid(dir string) *TestGrid {
+	return &TestGrid{Client: &http.Client{Timeout: DEFAULT_HTTP_TIMEOUT, Transport: &Recorder{Dir: dir}}}
+}
+
+// NewReplayingTestGrid returns a TestGrid source that serves the responses
+// recorded in dir, without making any requests
+func NewReplayingTestGrid(dir string) *TestGrid {
+	return &TestGrid{Client: &http.Client{Transport: &Replayer{Dir: dir}}}
+}
@@ -0,0 +1,86 @@
+package cistatus
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roundTripperFunc answers requests with a function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Tests that the summaries and tables recorded by a Recorder are served back
+// by a Replayer, and that requests that were not recorded are not found
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testgrid := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"a-job":{"overall_status":"FLAKY"}}`
+		if strings.HasSuffix(req.URL.Path, "/table") {
+			body = `{"test-group-name":"a-job","tests":[{"name":"a test"}]}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	recording := NewRecordingTestGrid(dir)
+	recording.Client.Transport.(*Recorder).Transport = testgrid
+	if _, err := recording.Summary("a-dashboard"); err != nil {
+		t.Fatalf("Recording summary returned %v", err)
+	}
+	if _, err := recording.JobTable("a-dashboard", "a-job"); err != nil {
+		t.Fatalf("Recording table returned %v", err)
+	}
+
+	replaying := NewReplayingTestGrid(dir)
+	jobs, err := replaying.Summary("a-dashboard")
+	if err != nil || jobs["a-job"].OverallStatus != FLAKY {
+		t.Errorf("Expected the recorded summary, got %v %v", jobs, err)
+	}
+	table, err := replaying.JobTable("a-dashboard", "a-job")
+	if err != nil || table.TestGroupName != "a-job" || len(table.Tests) != 1 {
+		t.Errorf("Expected the recorded table, got %+v %v", table, err)
+	}
+
+	var fe *FetchError
+	if _, err = replaying.JobTable("a-dashboard", "another-job"); !errors.As(err, &fe) || fe.Status != http.StatusNotFound {
+		t.Errorf("Expected a job that was not recorded to be not found, got %v", err)
+	}
+}
+
+// Tests that the fixture of a table keeps a short file name however long the
+// name of its job, and differs from the fixtures of other jobs
+func TestFixturePathLongJob(t *testing.T) {
+	job := strings.Repeat("a-long-job-name-", 50)
+	u, err := url.Parse("https://testgrid.k8s.io/a-dashboard/table?tab=" + job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := FixturePath("fixtures", u)
+	if name := filepath.Base(path); len(name) > 255 {
+		t.Errorf("Expected a file name of at most 255 bytes, got %d bytes", len(name))
+	}
+	if dir := filepath.Join("fixtures", "testgrid.k8s.io", "a-dashboard"); filepath.Dir(path) != dir {
+		t.Errorf("Expected the fixture in %s, got %s", dir, path)
+	}
+
+	other, _ := url.Parse("https://testgrid.k8s.io/a-dashboard/table?tab=" + job + "2")
+	if FixturePath("fixtures", other) == path {
+		t.Errorf("Expected different jobs to have different fixtures, both got %s", path)
+	}
+}
@@ -0,0 +1,153 @@
+package cistatus
+
+import (
+	"sort"
+	"time"
+)
+
+// TestStatus is the result of a single run of a test as encoded by TestGrid
+type TestStatus int
+
+const (
+	TEST_NO_RESULT         TestStatus = 0
+	TEST_PASS              TestStatus = 1
+	TEST_PASS_WITH_ERRORS  TestStatus = 2
+	TEST_PASS_WITH_SKIPS   TestStatus = 3
+	TEST_RUNNING           TestStatus = 4
+	TEST_CATEGORIZED_ABORT TestStatus = 5
+	TEST_UNKNOWN           TestStatus = 6
+	TEST_CANCEL            TestStatus = 7
+	TEST_BLOCKED           TestStatus = 8
+	TEST_TIMED_OUT         TestStatus = 9
+	TEST_CATEGORIZED_FAIL  TestStatus = 10
+	TEST_BUILD_FAIL        TestStatus = 11
+	TEST_FAIL              TestStatus = 12
+	TEST_FLAKY             TestStatus = 13
+	TEST_TOOL_FAIL         TestStatus = 14
+	TEST_BUILD_PASSED      TestStatus = 15
+)
+
+// StatusRun is a run length encoded sequence of Count runs of a test that all
+// had the TestStatus Value
+type StatusRun struct {
+	Count int `json:"count"`
+	Value int `json:"value"`
+}
+
+// TestStats summarises the run history of a test in a TestGrid test table
+type TestStats struct {
+	Runs        int       // runs that produced a result
+	Failures    int       // runs that failed
+	Flakes      int       // runs that TestGrid marked flaky or failed next to a pass
+	FlakeRate   float64   // Flakes / Runs
+	FailureRate float64   // Failures / Runs
+	LastFailure time.Time // start of the most recent failed run, zero if none
+}
+
+// passed returns true for statuses of runs where the test passed
+func (s TestStatus) passed() bool {
+	switch s {
+	case TEST_PASS, TEST_PASS_WITH_ERRORS, TEST_PASS_WITH_SKIPS, TEST_BUILD_PASSED:
+		return true
+	}
+	return false
+}
+
+// failed returns true for statuses of runs where the test failed
+func (s TestStatus) failed() bool {
+	switch s {
+	case TEST_CATEGORIZED_ABORT, TEST_TIMED_OUT, TEST_CATEGORIZED_FAIL,
+		TEST_BUILD_FAIL, TEST_FAIL, TEST_TOOL_FAIL:
+		return true
+	}
+	return false
+}
+
+// DecodeStatuses expands run length encoded statuses into one TestStatus per
+// run, in the same newest first order as the table columns
+func DecodeStatuses(runs []StatusRun) []TestStatus {
+	var statuses []TestStatus
+	for _, run := range runs {
+		for i := 0; i < run.Count; i++ {
+			statuses = append(statuses, TestStatus(run.Value))
+		}
+	}
+	return statuses
+}
+
+// CalculateStats works out the TestStats of a test from its run length encoded
+// statuses and the start timestamps in ms of the runs in the test table. Runs
+// that were cancelled, blocked, still running or have no result are ignored.
+// A run is counted as a flake if TestGrid marked it flaky, or if it failed and
+// the run before or after it passed.
+func CalculateStats(runs []StatusRun, timestamps []int64) TestStats {
+	var (
+		stats    TestStats
+		statuses = DecodeStatuses(runs)
+		observed []int // indices of runs that passed, failed or flaked
+	)
+
+	for i, status := range statuses {
+		if status.passed() || status.failed() || status == TEST_FLAKY {
+			observed = append(observed, i)
+		}
+	}
+
+	for k, i := range observed {
+		status := statuses[i]
+		switch {
+		case status == TEST_FLAKY:
+			stats.Flakes++
+		case status.failed():
+			stats.Failures++
+			if (k > 0 && statuses[observed[k-1]].passed()) ||
+				(k < len(observed)-1 && statuses[observed[k+1]].passed()) {
+				stats.Flakes++
+			}
+		default:
+			continue
+		}
+		if i < len(timestamps) {
+			at := time.Unix(0, timestamps[i]*int64(time.Millisecond))
+			if at.After(stats.LastFailure) {
+				stats.LastFailure = at
+			}
+		}
+	}
+
+	stats.Runs = len(observed)
+	if stats.Runs > 0 {
+		stats.FlakeRate = float64(stats.Flakes) / float64(stats.Runs)
+		stats.FailureRate = float64(stats.Failures) / float64(stats.Runs)
+	}
+	return stats
+}
+
+// addStatsToTestResults calculates the TestStats of each test in tgJobResult
+// and sorts the tests by severity
+func addStatsToTestResults(tgJobResult *TestGridJobResult) {
+	for i, t := range tgJobResult.Tests {
+		tgJobResult.Tests[i].Stats = CalculateStats(t.Statuses, tgJobResult.Timestamps)
+	}
+	tgJobResult.SortBySeverity()
+}
+
+// SortBySeverity orders the tests in r from the most to the least severe,
+// ranking by flake rate, then failure rate, then the number of runs observed
+func (r *TestGridJobResult) SortBySeverity() {
+	sort.SliceStable(r.Tests, func(i, j int) bool {
+		return MoreSevere(r.Tests[i].Stats, r.Tests[j].Stats)
+	})
+}
+
+// MoreSevere returns true if a test with stats a ranks above one with stats b,
+// see SortBySeverity
+func MoreSevere(a, b TestStats) bool {
+	if a.FlakeRate != b.FlakeRate {
+		return a.FlakeRate > b.FlakeRate
+	}
+	if a.FailureRate != b.FailureRate {
+		return a.FailureRate > b.FailureRate
+	}
+	return a.Runs > b.Runs
+}
@@ -0,0 +1,71 @@
+package cistatus
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests decoding of run length encoded statuses into per run results
+func TestDecodeStatuses(t *testing.T) {
+	statuses := DecodeStatuses([]StatusRun{{Count: 2, Value: 1}, {Count: 1, Value: 12}})
+	expected := []TestStatus{TEST_PASS, TEST_PASS, TEST_FAIL}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("Expected run %d to be %d, got %d", i, expected[i], statuses[i])
+		}
+	}
+}
+
+// Tests flake and failure rates for tests with different run histories,
+// runs are listed newest first as they are on TestGrid
+func TestCalculateStats(t *testing.T) {
+	timestamps := []int64{6000, 5000, 4000, 3000, 2000, 1000}
+	scenarios := []struct {
+		name        string
+		runs        []StatusRun
+		runCount    int
+		flakes      int
+		failures    int
+		lastFailure int64
+	}{
+		{"always passes", []StatusRun{{6, 1}}, 6, 0, 0, 0},
+		{"always fails", []StatusRun{{6, 12}}, 6, 0, 6, 6000},
+		{"fails then recovers", []StatusRun{{3, 1}, {3, 12}}, 6, 1, 3, 3000},
+		{"flips", []StatusRun{{1, 1}, {1, 12}, {1, 1}, {1, 12}, {2, 1}}, 6, 2, 2, 5000},
+		{"marked flaky", []StatusRun{{1, 13}, {5, 1}}, 6, 1, 0, 6000},
+		{"ignores no result", []StatusRun{{1, 12}, {2, 0}, {3, 1}}, 4, 1, 1, 6000},
+	}
+
+	for _, s := range scenarios {
+		stats := CalculateStats(s.runs, timestamps)
+		if stats.Runs != s.runCount || stats.Flakes != s.flakes || stats.Failures != s.failures {
+			t.Errorf("%s: expected %d runs %d flakes %d failures, got %+v",
+				s.name, s.runCount, s.flakes, s.failures, stats)
+		}
+		var lastFailure time.Time
+		if s.lastFailure > 0 {
+			lastFailure = time.Unix(0, s.lastFailure*int64(time.Millisecond))
+		}
+		if !stats.LastFailure.Equal(lastFailure) {
+			t.Errorf("%s: expected last failure at %v, got %v", s.name, lastFailure, stats.LastFailure)
+		}
+	}
+}
+
+// Tests that the flakiest tests are ranked first
+func TestSortBySeverity(t *testing.T) {
+	r := &TestGridJobResult{Tests: []TestResult{
+		{Name: "fails", Stats: TestStats{Runs: 4, FailureRate: 1}},
+		{Name: "flakes", Stats: TestStats{Runs: 4, FlakeRate: 0.5, FailureRate: 0.5}},
+		{Name: "flakes more", Stats: TestStats{Runs: 4, FlakeRate: 0.75, FailureRate: 0.5}},
+	}}
+	r.SortBySeverity()
+	for i, name := range []string{"flakes more", "flakes", "fails"} {
+		if r.Tests[i].Name != name {
+			t.Errorf("Expected %s at %d, got %s", name, i, r.Tests[i].Name)
+		}
+	}
+}
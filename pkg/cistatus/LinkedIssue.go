@@ -0,0 +1,28 @@
+package cistatus
+
+import "time"
+
+const (
+	ISSUE_OPEN   string = "open"
+	ISSUE_CLOSED string = "closed"
+)
+
+// LinkedIssue is a GitHub issue tracking a test, see TestResult.LinkedBugs
+type LinkedIssue struct {
+	Number    int       `json:"number"`
+	Repo      string    `json:"repo"` // owner/name
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	State     string    `json:"state"`
+	Labels    []string  `json:"labels,omitempty"`
+	Assignees []string  `json:"assignees,omitempty"`
+	Column    string    `json:"column,omitempty"`  // Project board column, or status, of the issue
+	Sources   []string  `json:"sources,omitempty"` // Boards and searches the issue was discovered by
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsOpen returns true unless i has been closed
+func (i LinkedIssue) IsOpen() bool {
+	return i.State != ISSUE_CLOSED
+}
@@ -0,0 +1,55 @@
+package cistatus
+
+import "sort"
+
+// OverallStatus is the status TestGrid gives a job on a dashboard summary
+type OverallStatus string
+
+const (
+	PASSING    OverallStatus = "PASSING"
+	FAILING    OverallStatus = "FAILING"
+	FLAKY      OverallStatus = "FLAKY"
+	ACCEPTABLE OverallStatus = "ACCEPTABLE"
+	STALE      OverallStatus = "STALE"
+	BROKEN     OverallStatus = "BROKEN"
+	PENDING    OverallStatus = "PENDING"
+	UNKNOWN    OverallStatus = "UNKNOWN"
+)
+
+// KnownStatuses lists the TestGrid job statuses in the order they are reported
+var KnownStatuses = []OverallStatus{
+	FAILING, FLAKY, BROKEN, STALE, PENDING, ACCEPTABLE, PASSING, UNKNOWN,
+}
+
+// Known returns true if s is one of KnownStatuses
+func (s OverallStatus) Known() bool {
+	for _, known := range KnownStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// rank returns the position of s in KnownStatuses, which lists the most
+// severe statuses first, after every known status if s is not known
+func (s OverallStatus) rank() int {
+	for i, known := range KnownStatuses {
+		if s == known {
+			return i
+		}
+	}
+	return len(KnownStatuses)
+}
+
+// sortStatuses orders statuses as per KnownStatuses followed by any statuses
+// TestGrid reported that are not known, in alphabetical order
+func sortStatuses(statuses []OverallStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		ri, rj := statuses[i].rank(), statuses[j].rank()
+		if ri != rj {
+			return ri < rj
+		}
+		return statuses[i] < statuses[j]
+	})
+}
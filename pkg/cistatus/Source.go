@@ -0,0 +1,12 @@
+package cistatus
+
+// Source is a backend that CI status data can be collected from. The report
+// logic only sees JobStatus and TestGridJobResult, so backends other than
+// TestGrid (BigQuery exports, GCS dumps, local fixtures) need to translate
+// their data into those types.
+type Source interface {
+	// Summary returns the status of each job on dashboard keyed by job name
+	Summary(dashboard string) (map[string]JobStatus, error)
+	// JobTable returns the results of the tests run by job on dashboard
+	JobTable(dashboard, job string) (*TestGridJobResult, error)
+}
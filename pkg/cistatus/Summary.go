@@ -1,22 +1,21 @@
 package cistatus
 
 // Retrieves CI Status from summary report for a named TestGrid TabGroup
-// Retrieves data from TestGrid for now
+// Retrieves data through a Source, TestGrid over HTTP by default
 // TODO BigQuery should be the single source of truth)
 import (
-	"encoding/json"
 	"fmt"
-	log "github.com/sirupsen/logrus"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 const (
-	TG_TABGROUP_SUMMARY_FMT string = "https://testgrid.k8s.io/%s/summary"
-	TG_JOB_TEST_TABLE_FMT   string = "https://testgrid.k8s.io/%s/table?tab=%s&width=5&exclude-non-failed-tests=&sort-by-flakiness=&dashboard=%s"
+	DEFAULT_WORKERS int = 8
 )
 
 // TabGroupStatus tracks status of CI Jobs for a named TestGrid TabGroup
@@ -25,26 +24,30 @@ type CiStatus struct {
 	CollectedAt        time.Time
 	Count              int
 	TabGroupSummaryUrl string
-	FlakingJobs        map[string]JobStatus
-	PassingJobs        map[string]JobStatus
-	FailedJobs         map[string]JobStatus
-	Logger             *log.Logger
+	Jobs               map[OverallStatus]map[string]JobStatus // keyed by status then job name
+	Logger             *log.Logger                            `json:"-"`
+	Source             Source                                 `json:"-"` // defaults to TestGrid when nil
+	Workers            int                                    `json:"-"` // number of job test tables fetched concurrently
 }
 
 // JobStatus mirrors data on the TestGrid summary status
 type JobStatus struct {
-	OverallStatus           string             `json:"overall_status"`
+	OverallStatus           OverallStatus      `json:"overall_status"`
 	Alert                   string             `json:"alert"`
 	LastRun                 int64              `json:"last_run_timestamp"`
 	LastUpdate              int64              `json:"last_update_timestamp"`
 	LatestGreenRun          string             `json:"latest_green"`
 	LatestStatusIcon        string             `json:"overall_status_icon"`
 	LatestStatusDescription string             `json:"status"`
-	Url                     string             // Url for testGridJobResult
-	JobTestResults          *testGridJobResult // See CollectFlakyTests
+	Url                     string             // Url for TestGridJobResult
+	JobTestResults          *TestGridJobResult // See CollectFlakyTests
+	Dashboard               string             `json:"dashboard"`             // See CollectStatus
+	AlsoOn                  []string           `json:"also_on,omitempty"`     // See Collection.Collect
+	FetchError              string             `json:"fetch_error,omitempty"` // Why JobTestResults is unknown
 }
 
-type testGridJobResult struct {
+// TestGridJobResult mirrors the test table TestGrid shows for a single job
+type TestGridJobResult struct {
 	TestGroupName string `json:"test-group-name"`
 	/* - Unused fields from REST query
 	           - Retained as comment for possible future use
@@ -61,31 +64,16 @@ type testGridJobResult struct {
 				Bugs    struct {
 				} `json:"bugs"`
 				Changelists       []string   `json:"changelists"`
-				ColumnIds         []string   `json:"column_ids"`
 				CustomColumns     [][]string `json:"custom-columns"`
 				ColumnHeaderNames []string   `json:"column-header-names"`
 				Groups            []string   `json:"groups"`
 				Metrics           []string   `json:"metrics"`
 	*/
-	Tests []struct {
-		Name         string        `json:"name"`
-		OriginalName string        `json:"original-name"`
-		Alert        interface{}   `json:"alert"`
-		LinkedBugs   []interface{} `json:"linked_bugs"`
-		Messages     []string      `json:"messages"`
-		ShortTexts   []string      `json:"short_texts"`
-		Statuses     []struct {
-			Count int `json:"count"`
-			Value int `json:"value"`
-		} `json:"statuses"`
-		Target       string      `json:"target"`
-		UserProperty interface{} `json:"user_property"`
-		// Calculated Field added here
-		Sig string
-	} `json:"tests"`
+	ColumnIds  []string     `json:"column_ids"` // One per run, newest first
+	Timestamps []int64      `json:"timestamps"` // Start of each run in ms
+	Tests      []TestResult `json:"tests"`
 	/*  Remainder of Unused fields
 		RowIds       []string    `json:"row_ids"`
-		Timestamps   []int64     `json:"timestamps"`
 		Clusters     interface{} `json:"clusters"`
 		TestIDMap    interface{} `json:"test_id_map"`
 		TestMetadata struct {
@@ -151,72 +139,110 @@ type testGridJobResult struct {
 }
 
 // CollectFlakyTest queries TestGrid for a list of flaking tests for each Job
-// that is currently Flaky and adds the tests to
+// that is currently Flaky and adds the tests to its JobTestResults. Jobs whose
+// tests could not be retrieved are returned in a JobErrors.
 func (t *CiStatus) CollectFlakyTests() error {
+	return t.collectTests(t.Jobs[FLAKY])
+}
 
-	for jobName := range t.FlakingJobs {
-		url := fmt.Sprintf(TG_JOB_TEST_TABLE_FMT,
-			t.Name, url.QueryEscape(jobName), t.Name)
+// CollectFailedTests adds a list of Tests that are failing for each Failed Job.
+// Jobs whose tests could not be retrieved are returned in a JobErrors.
+func (t *CiStatus) CollectFailedTests() error {
+	return t.collectTests(t.Jobs[FAILING])
+}
 
-		resp, err := http.Get(url)
+// collectTests fetches the test table of each job in jobs using a pool of
+// t.Workers goroutines. A job whose table can not be fetched keeps a nil
+// JobTestResults and has the reason recorded in its FetchError.
+func (t *CiStatus) collectTests(jobs map[string]JobStatus) error {
+	type fetched struct {
+		jobName string
+		results *TestGridJobResult
+		err     error
+	}
 
-		if err != nil {
-			t.Logger.Error("HTTP get job test results", err, url)
-			return err
-		}
+	var (
+		src      = t.source()
+		jobNames = make(chan string)
+		results  = make(chan fetched)
+		wg       sync.WaitGroup
+	)
 
-		body, err := ioutil.ReadAll(resp.Body)
-		var flakingTestResults testGridJobResult
-		err = json.Unmarshal(body, &flakingTestResults)
-		if err != nil {
-			t.Logger.Error("Unmarshalling Test Result", err, url)
-			return err
-		}
-		// SearchLoggedIssues()
-		// Store data and url where we found it. tmp var used as per
-		// https://github.com/golang/go/issues/3117#issuecomment-66063615
-		var tmp = t.FlakingJobs[jobName]
-		addSigToTestResults(&flakingTestResults)
-		tmp.JobTestResults = &flakingTestResults
-		tmp.Url = url
-		t.FlakingJobs[jobName] = tmp
+	workers := t.Workers
+	if workers < 1 {
+		workers = DEFAULT_WORKERS
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jobName := range jobNames {
+				r, err := src.JobTable(t.Name, jobName)
+				results <- fetched{jobName: jobName, results: r, err: err}
+			}
+		}()
 	}
-	return nil
-}
-
-// CollectFailedTests adds a list of Tests that are failing for each Failed Job
-func (t *CiStatus) CollectFailedTests() error {
 
-	for jobName := range t.FailedJobs {
-		url := fmt.Sprintf(TG_JOB_TEST_TABLE_FMT,
-			t.Name, url.QueryEscape(jobName), t.Name)
-		resp, err := http.Get(url)
-		if err != nil {
-			t.Logger.Error("HTTP get Failed job test results", err, url)
-			return err
-		}
-		body, err := ioutil.ReadAll(resp.Body)
-		var failedTestResults testGridJobResult
-		err = json.Unmarshal(body, &failedTestResults)
-		if err != nil {
-			t.Logger.Error("Unmarshalling Failed Test Result", err, url)
-			return err
+	// names are copied out of jobs so that jobs can be updated as results arrive
+	names := make([]string, 0, len(jobs))
+	for jobName := range jobs {
+		names = append(names, jobName)
+	}
+	go func() {
+		for _, jobName := range names {
+			jobNames <- jobName
 		}
+		close(jobNames)
+		wg.Wait()
+		close(results)
+	}()
+
+	errs := make(JobErrors)
+	for f := range results {
 		// Store data and url where we found it. tmp var used as per
 		// https://github.com/golang/go/issues/3117#issuecomment-66063615
-		var tmp = t.FailedJobs[jobName]
-		addSigToTestResults(&failedTestResults)
-		tmp.JobTestResults = &failedTestResults
-		tmp.Url = url
-		t.FailedJobs[jobName] = tmp
+		var tmp = jobs[f.jobName]
+		tmp.Url = JobTableUrl(t.Name, f.jobName)
+		if f.err != nil {
+			t.Logger.Error("Getting job test results", f.err, f.jobName)
+			tmp.JobTestResults = nil
+			tmp.FetchError = f.err.Error()
+			errs[f.jobName] = f.err
+		} else {
+			addSigToTestResults(f.results)
+			addStatsToTestResults(f.results)
+			tmp.JobTestResults = f.results
+			tmp.FetchError = ""
+		}
+		jobs[f.jobName] = tmp
+	}
+
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
+// TestResult is a row of the TestGrid test table of a job
+type TestResult struct {
+	Name         string        `json:"name"`
+	OriginalName string        `json:"original-name"`
+	Alert        interface{}   `json:"alert"`
+	LinkedBugs   []LinkedIssue `json:"linked_issues,omitempty"` // TestGrid's own linked_bugs are not kept
+	Messages     []string      `json:"messages"`
+	ShortTexts   []string      `json:"short_texts"`
+	Statuses     []StatusRun   `json:"statuses"` // See DecodeStatuses
+	Target       string        `json:"target"`
+	UserProperty interface{}   `json:"user_property"`
+	// Calculated Fields added here
+	Sig   string
+	Stats TestStats // See addStatsToTestResults
+}
+
 // addSigToTestResults sets the sig field on tgJobResult using the test name
 // by finding the first occurance of [sig-SIGNAME], if no sig is found sets sig
 // to "job-owner"
-func addSigToTestResults(tgJobResult *testGridJobResult) {
+func addSigToTestResults(tgJobResult *TestGridJobResult) {
 	var sigRe = regexp.MustCompile(`\[sig-.+?\] `)
 	for i, t := range tgJobResult.Tests {
 		sig := sigRe.FindString(t.Name)
@@ -229,53 +255,67 @@ func addSigToTestResults(tgJobResult *testGridJobResult) {
 	return
 }
 
-// CollectStatus populates t with job status summary data from TestGrid
+// CollectStatus populates t with job status summary data from its Source
 func (t *CiStatus) CollectStatus() error {
 
-	t.TabGroupSummaryUrl = fmt.Sprintf(TG_TABGROUP_SUMMARY_FMT, t.Name)
+	t.TabGroupSummaryUrl = SummaryUrl(t.Name)
 
-	resp, err := http.Get(t.TabGroupSummaryUrl)
+	jobs, err := t.source().Summary(t.Name)
 	if err != nil {
-		t.Logger.Error("HTTP getting", err)
+		t.Logger.Error("Getting job status summary", err)
 		return err
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		t.Logger.Error("Reading HTTP response buffer", err)
-		return err
+	// Every job is kept, including those with a status that is not known, so
+	// that the jobs in t.Jobs always add up to t.Count
+	t.Jobs = make(map[OverallStatus]map[string]JobStatus)
+	for name, job := range jobs {
+		job.Dashboard = t.Name
+		if job.OverallStatus == "" {
+			job.OverallStatus = UNKNOWN
+		}
+		if !job.OverallStatus.Known() {
+			t.Logger.Warnf("Job %s has unknown status %s", name, job.OverallStatus)
+		}
+		if t.Jobs[job.OverallStatus] == nil {
+			t.Jobs[job.OverallStatus] = make(map[string]JobStatus)
+		}
+		t.Jobs[job.OverallStatus][name] = job
 	}
 
-	jobs := make(map[string]JobStatus)
+	t.Count = len(jobs)
+	return nil
+}
 
-	err = json.Unmarshal(body, &jobs)
-	if err != nil {
-		t.Logger.Error("UnMarshalling reponse body", err)
-		return err
+// Statuses returns the statuses of the jobs in t in reporting order, see
+// KnownStatuses
+func (t *CiStatus) Statuses() []OverallStatus {
+	statuses := make([]OverallStatus, 0, len(t.Jobs))
+	for status := range t.Jobs {
+		statuses = append(statuses, status)
 	}
+	sortStatuses(statuses)
+	return statuses
+}
 
-	t.FlakingJobs = make(map[string]JobStatus, 0)
-
-	for name, job := range jobs {
-		if job.OverallStatus == "FLAKY" {
-			t.FlakingJobs[name] = jobs[name]
-		}
+// source returns the Source t collects from, TestGrid unless one was set
+func (t *CiStatus) source() Source {
+	if t.Source == nil {
+		t.Source = &TestGrid{}
 	}
+	return t.Source
+}
 
-	t.FailedJobs = make(map[string]JobStatus, 0)
-	for name, job := range jobs {
-		if job.OverallStatus == "FAILING" {
-			t.FailedJobs[name] = job
-		}
-	}
+// JobErrors maps the names of jobs whose test results could not be retrieved
+// to the error encountered retrieving them
+type JobErrors map[string]error
 
-	t.PassingJobs = make(map[string]JobStatus, 0)
-	for name, job := range jobs {
-		if job.OverallStatus == "PASSING" {
-			t.PassingJobs[name] = job
-		}
+func (e JobErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for jobName := range e {
+		names = append(names, jobName)
 	}
-
-	t.Count = len(jobs)
-	return nil
+	sort.Strings(names)
+	return fmt.Sprintf("Could not retrieve test results for %d job(s): %s",
+		len(names), strings.Join(names, ", "))
 }
@@ -0,0 +1,123 @@
+package cistatus
+
+import (
+	"errors"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fixtureSource is a Source that serves canned data, keyed by job name
+type fixtureSource struct {
+	jobs   map[string]JobStatus
+	tables map[string]*TestGridJobResult
+}
+
+func (f *fixtureSource) Summary(dashboard string) (map[string]JobStatus, error) {
+	return f.jobs, nil
+}
+
+func (f *fixtureSource) JobTable(dashboard, job string) (*TestGridJobResult, error) {
+	table, exists := f.tables[job]
+	if !exists {
+		return nil, errors.New("no fixture for " + job)
+	}
+	return table, nil
+}
+
+// Tests that CiStatus collects from whatever Source it is given
+func TestCollectFromSource(t *testing.T) {
+	flaky := &TestGridJobResult{TestGroupName: "flaky-job"}
+	src := &fixtureSource{
+		jobs: map[string]JobStatus{
+			"flaky-job":   {OverallStatus: "FLAKY"},
+			"passing-job": {OverallStatus: "PASSING"},
+		},
+		tables: map[string]*TestGridJobResult{"flaky-job": flaky},
+	}
+	cs := &CiStatus{Name: "fixture-dashboard", Logger: log.New(), Source: src}
+
+	if err := cs.CollectStatus(); err != nil {
+		t.Fatalf("CollectStatus returned %v", err)
+	}
+	if err := cs.CollectFlakyTests(); err != nil {
+		t.Fatalf("CollectFlakyTests returned %v", err)
+	}
+	if cs.Count != 2 || len(cs.Jobs[FLAKY]) != 1 || len(cs.Jobs[PASSING]) != 1 {
+		t.Errorf("Unexpected job counts %d %v %v", cs.Count, cs.Jobs[FLAKY], cs.Jobs[PASSING])
+	}
+	job := cs.Jobs[FLAKY]["flaky-job"]
+	if job.JobTestResults != flaky {
+		t.Errorf("Expected test results from source, got %v", job.JobTestResults)
+	}
+	if job.Url != JobTableUrl("fixture-dashboard", "flaky-job") {
+		t.Errorf("Unexpected job url %s", job.Url)
+	}
+}
+
+// Tests that a job whose tests can not be fetched is recorded rather than
+// aborting collection of the other jobs
+func TestCollectRecordsJobErrors(t *testing.T) {
+	jobs := map[string]JobStatus{"missing-job": {OverallStatus: "FLAKY"}}
+	tables := map[string]*TestGridJobResult{}
+	for _, jobName := range []string{"job-a", "job-b", "job-c"} {
+		jobs[jobName] = JobStatus{OverallStatus: "FLAKY"}
+		tables[jobName] = &TestGridJobResult{TestGroupName: jobName}
+	}
+	cs := &CiStatus{
+		Name:    "fixture-dashboard",
+		Logger:  log.New(),
+		Source:  &fixtureSource{jobs: jobs, tables: tables},
+		Workers: 2,
+	}
+	cs.CollectStatus()
+
+	err := cs.CollectFlakyTests()
+	jobErrs, ok := err.(JobErrors)
+	if !ok || len(jobErrs) != 1 || jobErrs["missing-job"] == nil {
+		t.Fatalf("Expected JobErrors for missing-job, got %v", err)
+	}
+	for jobName, job := range cs.Jobs[FLAKY] {
+		if jobName == "missing-job" {
+			if job.JobTestResults != nil || job.FetchError == "" {
+				t.Errorf("Expected missing-job to be unknown, got %v", job)
+			}
+		} else if job.JobTestResults == nil || job.JobTestResults.TestGroupName != jobName {
+			t.Errorf("Expected %s test results, got %v", jobName, job.JobTestResults)
+		}
+	}
+}
+
+// Tests that jobs with statuses other than FLAKY, FAILING and PASSING,
+// including statuses that are not known, are kept
+func TestCollectStatusKeepsEveryStatus(t *testing.T) {
+	src := &fixtureSource{
+		jobs: map[string]JobStatus{
+			"stale-job":   {OverallStatus: "STALE"},
+			"broken-job":  {OverallStatus: "BROKEN"},
+			"odd-job":     {OverallStatus: "SOMETHING_NEW"},
+			"blank-job":   {},
+			"passing-job": {OverallStatus: "PASSING"},
+		},
+	}
+	cs := &CiStatus{Name: "fixture-dashboard", Logger: log.New(), Source: src}
+	if err := cs.CollectStatus(); err != nil {
+		t.Fatalf("CollectStatus returned %v", err)
+	}
+
+	expected := []OverallStatus{BROKEN, STALE, PASSING, UNKNOWN, "SOMETHING_NEW"}
+	statuses := cs.Statuses()
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected statuses %v, got %v", expected, statuses)
+	}
+	total := 0
+	for i, status := range statuses {
+		if status != expected[i] {
+			t.Errorf("Expected status %d to be %s, got %s", i, expected[i], status)
+		}
+		total += len(cs.Jobs[status])
+	}
+	if total != cs.Count {
+		t.Errorf("Jobs per status add up to %d, expected %d", total, cs.Count)
+	}
+}
@@ -0,0 +1,80 @@
+package cistatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	TG_TABGROUP_SUMMARY_FMT string = "https://testgrid.k8s.io/%s/summary"
+	TG_JOB_TEST_TABLE_FMT   string = "https://testgrid.k8s.io/%s/table?tab=%s&width=5&exclude-non-failed-tests=&sort-by-flakiness=&dashboard=%s"
+	TG_DASHBOARD_JOB_FMT    string = "https://testgrid.k8s.io/%s#%s"
+)
+
+// TestGrid is a Source that scrapes the TestGrid JSON endpoints over HTTP.
+// Errors retrieving data are returned as a *FetchError, any other error means
+// TestGrid returned data that could not be unmarshalled.
+type TestGrid struct {
+	Client *http.Client // the package's shared client when nil
+	Retry  *RetryPolicy // DefaultRetryPolicy when nil
+}
+
+// SummaryUrl returns the TestGrid summary url for dashboard
+func SummaryUrl(dashboard string) string {
+	return fmt.Sprintf(TG_TABGROUP_SUMMARY_FMT, dashboard)
+}
+
+// JobTableUrl returns the TestGrid test table url for job on dashboard
+func JobTableUrl(dashboard, job string) string {
+	return fmt.Sprintf(TG_JOB_TEST_TABLE_FMT,
+		dashboard, url.QueryEscape(job), dashboard)
+}
+
+// Summary retrieves the TestGrid summary for dashboard
+func (tg *TestGrid) Summary(dashboard string) (map[string]JobStatus, error) {
+	jobs := make(map[string]JobStatus)
+	err := tg.getJSON(SummaryUrl(dashboard), &jobs)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// JobTable retrieves the TestGrid test table for job on dashboard
+func (tg *TestGrid) JobTable(dashboard, job string) (*TestGridJobResult, error) {
+	var result TestGridJobResult
+	err := tg.getJSON(JobTableUrl(dashboard, job), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getJSON unmarshals the body returned by a GET on url into v
+func (tg *TestGrid) getJSON(url string, v interface{}) error {
+	client := tg.Client
+	if client == nil {
+		client = httpClient
+	}
+	policy := DefaultRetryPolicy
+	if tg.Retry != nil {
+		policy = *tg.Retry
+	}
+
+	body, err := policy.get(client, url)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("Unmarshalling response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// DashboardJobUrl returns the url of the TestGrid page showing job on dashboard
+func DashboardJobUrl(dashboard, job string) string {
+	return fmt.Sprintf(TG_DASHBOARD_JOB_FMT, dashboard, url.PathEscape(job))
+}
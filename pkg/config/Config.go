@@ -0,0 +1,364 @@
+package config
+
+// Loads the collector configuration from a YAML file, see the README for an
+// example
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/httpcache"
+	"github.com/RobertKielty/flake-tracker/pkg/report"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	STDOUT string = "-" // Output path that writes to stdout
+
+	BOARD_CLASSIC      string = "classic"     // Classic project board read over REST
+	BOARD_PROJECTS_V2  string = "projects-v2" // Projects (v2) board read over GraphQL
+	OWNER_ORGANIZATION string = "organization"
+	OWNER_USER         string = "user"
+
+	CACHE_GITHUB   string = "github"   // Cache source of GitHub API responses
+	CACHE_TESTGRID string = "testgrid" // Cache source of TestGrid summaries and tables
+
+	DEFAULT_CACHE_MAX_AGE time.Duration = 7 * 24 * time.Hour
+)
+
+var (
+	// CacheSources are the sources Cache.TTL can be set for
+	CacheSources = []string{CACHE_GITHUB, CACHE_TESTGRID}
+
+	// DefaultCacheTTL is the TTL of the sources that are not in Cache.TTL
+	DefaultCacheTTL = map[string]time.Duration{
+		CACHE_GITHUB:   5 * time.Minute,
+		CACHE_TESTGRID: 15 * time.Minute,
+	}
+)
+
+// Config describes what the collector collects and where it reports it
+type Config struct {
+	Dashboards []string `yaml:"dashboards"`
+	TestGrid   TestGrid `yaml:"testgrid"`
+	Boards     []Board  `yaml:"boards"`
+	Searches   []string `yaml:"searches"` // GitHub issue searches that find flake issues not on Boards
+	Outputs    []Output `yaml:"outputs"`
+	Store      Store    `yaml:"store"`
+	Cache      Cache    `yaml:"cache"`
+	Logging    Logging  `yaml:"logging"`
+}
+
+// TestGrid configures how CI status is collected from TestGrid. Responses
+// are saved as fixtures in Record, or served from the fixtures in Replay
+// instead of fetching them, see cistatus.Recorder.
+type TestGrid struct {
+	Workers int    `yaml:"workers"`
+	Record  string `yaml:"record"`
+	Replay  string `yaml:"replay"`
+}
+
+// Source returns the TestGrid source configured by t, caching responses in
+// cache, along with the cache's transport, nil if responses are not cached
+func (t TestGrid) Source(cache Cache) (*cistatus.TestGrid, *httpcache.Transport) {
+	if t.Replay != "" {
+		return cistatus.NewReplayingTestGrid(t.Replay), nil
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	cached := cache.Transport(CACHE_TESTGRID, nil)
+	if cached != nil {
+		transport = cached
+	}
+	if t.Record != "" { // record responses served from the cache too
+		transport = &cistatus.Recorder{Dir: t.Record, Transport: transport}
+	}
+	return &cistatus.TestGrid{Client: &http.Client{Timeout: cistatus.DEFAULT_HTTP_TIMEOUT, Transport: transport}}, cached
+}
+
+// Board is a GitHub project board that flake issues are tracked on. A
+// classic board is identified by ID, Columns maps the names of its columns to
+// their IDs. A projects-v2 board is identified by its Owner and Number, the
+// column of an issue is the value of its StatusField.
+type Board struct {
+	Type        string           `yaml:"type"` // BOARD_CLASSIC when empty
+	ID          int64            `yaml:"id"`
+	Columns     map[string]int64 `yaml:"columns"`
+	Owner       string           `yaml:"owner"`
+	OwnerType   string           `yaml:"owner_type"` // OWNER_ORGANIZATION when empty
+	Number      int              `yaml:"number"`
+	StatusField string           `yaml:"status_field"`
+}
+
+// Reader returns the reader for the issues on b
+func (b Board) Reader() rf.BoardReader {
+	if b.Type == BOARD_PROJECTS_V2 {
+		return &rf.ProjectV2Board{
+			Owner:       b.Owner,
+			User:        b.OwnerType == OWNER_USER,
+			Number:      b.Number,
+			StatusField: b.StatusField,
+		}
+	}
+	columns := make(map[int64]string)
+	for name, id := range b.Columns {
+		columns[id] = name
+	}
+	return &rf.ClassicBoard{ID: b.ID, Columns: columns}
+}
+
+// Output is a report format and the file it is written to, STDOUT or an
+// empty path writes to stdout
+type Output struct {
+	Format string `yaml:"format"`
+	Path   string `yaml:"path"`
+}
+
+// Store is where snapshots of each collection are kept
+type Store struct {
+	Path string `yaml:"path"`
+}
+
+// Cache is where HTTP responses are kept between runs, in a directory per
+// source. Responses are reused for the TTL of their source, after which
+// GitHub responses are revalidated with their ETag so that unchanged ones do
+// not use any quota. Refresh ignores the TTLs for a run. Responses older than
+// MaxAge are removed by Prune, 0 keeps them forever. An empty Path disables
+// the cache.
+type Cache struct {
+	Path    string                   `yaml:"path"`
+	TTL     map[string]time.Duration `yaml:"ttl"` // by source, DefaultCacheTTL for sources not listed
+	MaxAge  time.Duration            `yaml:"max_age"`
+	Refresh bool                     `yaml:"-"`
+}
+
+// Transport returns a Transport caching the responses from source, making
+// requests with next, nil if the cache is disabled
+func (c Cache) Transport(source string, next http.RoundTripper) *httpcache.Transport {
+	store := c.Store(source)
+	if store == nil {
+		return nil
+	}
+	ttl, ok := c.TTL[source]
+	if !ok {
+		ttl = DefaultCacheTTL[source]
+	}
+	return &httpcache.Transport{Store: store, TTL: ttl, Refresh: c.Refresh, Transport: next}
+}
+
+// Prune removes the responses of every source cached longer than MaxAge
+// before now, returning how many were removed
+func (c Cache) Prune(now time.Time) (int, error) {
+	if c.MaxAge == 0 {
+		return 0, nil
+	}
+	total := 0
+	for _, source := range CacheSources {
+		store := c.Store(source)
+		if store == nil {
+			continue
+		}
+		removed, err := store.Prune(now.Add(-c.MaxAge))
+		total += removed
+		if err != nil {
+			return total, fmt.Errorf("Pruning %s cache: %w", source, err)
+		}
+	}
+	return total, nil
+}
+
+// Store returns the store of the responses from source, nil if the cache is
+// disabled
+func (c Cache) Store(source string) *httpcache.Store {
+	if c.Path == "" {
+		return nil
+	}
+	return &httpcache.Store{Dir: filepath.Join(c.Path, source)}
+}
+
+// Logging configures the log file written for each run, one per logger and
+// day named <name>-<date>.log in Dir with the date formatted as DateFormat
+type Logging struct {
+	Dir        string `yaml:"dir"`
+	Level      string `yaml:"level"`
+	DateFormat string `yaml:"date_format"`
+}
+
+// Default returns the configuration used when no file is given
+func Default() *Config {
+	return &Config{
+		Dashboards: []string{"sig-release-master-blocking", "sig-release-master-informing"},
+		TestGrid:   TestGrid{Workers: cistatus.DEFAULT_WORKERS},
+		Boards: []Board{{
+			ID: rf.CI_SIGNAL_BOARD_ID,
+			Columns: map[string]int64{
+				"new":                 rf.CI_SIGNAL_NEW_CARD_COL_ID,
+				"under investigation": rf.CI_SIGNAL_UNDER_INVESTIGATION_COL_ID,
+				"observing":           rf.CI_SIGNAL_OBSERVING_COL_ID,
+			},
+		}},
+		Searches: []string{rf.DEFAULT_FLAKE_SEARCH},
+		Outputs:  []Output{{Format: report.FORMAT_CSV, Path: STDOUT}},
+		Store:    Store{Path: "snapshots"},
+		Cache:    Cache{Path: "cache", MaxAge: DEFAULT_CACHE_MAX_AGE},
+		Logging:  Logging{Dir: ".", Level: "trace", DateFormat: "Jan-02-2006"},
+	}
+}
+
+// Load reads the configuration in the YAML file at path on top of Default.
+// Keys that are not part of the configuration are rejected.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Default()
+	if err = yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ValidationError is a problem with the value of Key in a Config
+type ValidationError struct {
+	Key     string
+	Problem string
+}
+
+func (e ValidationError) Error() string {
+	return e.Key + ": " + e.Problem
+}
+
+// ValidationErrors lists every problem found in a Config
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "Invalid configuration\n  " + strings.Join(msgs, "\n  ")
+}
+
+// Validate checks c, returning ValidationErrors naming each offending key
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+	add := func(key, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Key: key, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Dashboards) == 0 {
+		add("dashboards", "at least one TestGrid dashboard is required")
+	}
+	seen := make(map[string]bool)
+	for i, d := range c.Dashboards {
+		key := fmt.Sprintf("dashboards[%d]", i)
+		if strings.TrimSpace(d) == "" {
+			add(key, "dashboard name is empty")
+		} else if seen[d] {
+			add(key, "dashboard %s is listed more than once", d)
+		}
+		seen[d] = true
+	}
+
+	if c.TestGrid.Workers < 1 {
+		add("testgrid.workers", "must be at least 1, got %d", c.TestGrid.Workers)
+	}
+	if c.TestGrid.Record != "" && c.TestGrid.Replay != "" {
+		add("testgrid.replay", "can not replay fixtures while recording them")
+	}
+
+	for i, b := range c.Boards {
+		key := fmt.Sprintf("boards[%d]", i)
+		switch b.Type {
+		case "", BOARD_CLASSIC:
+			if b.ID <= 0 {
+				add(key+".id", "project board id is required")
+			}
+			for name, id := range b.Columns {
+				if id <= 0 {
+					add(fmt.Sprintf("%s.columns.%s", key, name), "column id must be positive, got %d", id)
+				}
+			}
+		case BOARD_PROJECTS_V2:
+			if b.Owner == "" {
+				add(key+".owner", "login of the organization or user owning the project is required")
+			}
+			if b.OwnerType != "" && b.OwnerType != OWNER_ORGANIZATION && b.OwnerType != OWNER_USER {
+				add(key+".owner_type", "expected %s or %s, got %q", OWNER_ORGANIZATION, OWNER_USER, b.OwnerType)
+			}
+			if b.Number <= 0 {
+				add(key+".number", "project number is required")
+			}
+			if len(b.Columns) > 0 {
+				add(key+".columns", "columns are only used by %s boards, %s boards use status_field", BOARD_CLASSIC, BOARD_PROJECTS_V2)
+			}
+		default:
+			add(key+".type", "expected %s or %s, got %q", BOARD_CLASSIC, BOARD_PROJECTS_V2, b.Type)
+		}
+	}
+
+	for i, q := range c.Searches {
+		if strings.TrimSpace(q) == "" {
+			add(fmt.Sprintf("searches[%d]", i), "search query is empty")
+		}
+	}
+
+	if len(c.Outputs) == 0 {
+		add("outputs", "at least one output is required")
+	}
+	for i, o := range c.Outputs {
+		if _, err := report.New(o.Format); err != nil {
+			add(fmt.Sprintf("outputs[%d].format", i), "%v", err)
+		}
+	}
+
+	if c.Store.Path == "" {
+		add("store.path", "snapshot store path is required")
+	}
+
+	var sources []string
+	for source := range c.Cache.TTL {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		ttl, key := c.Cache.TTL[source], "cache.ttl."+source
+		if !contains(CacheSources, source) {
+			add(key, "expected one of %s", strings.Join(CacheSources, ", "))
+		} else if ttl < 0 {
+			add(key, "must not be negative, got %s", ttl)
+		}
+	}
+	if c.Cache.MaxAge < 0 {
+		add("cache.max_age", "must not be negative, got %s", c.Cache.MaxAge)
+	}
+
+	if _, err := log.ParseLevel(c.Logging.Level); err != nil {
+		add("logging.level", "%v", err)
+	}
+	if c.Logging.DateFormat == "" {
+		add("logging.date_format", "date format is required")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// contains returns true if s is one of values
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+// writeConfig writes yaml to a temporary file returning its path
+func writeConfig(t *testing.T, yaml string) string {
+	f, err := ioutil.TempFile("", "config-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(yaml); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+// Tests that a config file overrides the defaults it sets
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+dashboards:
+  - sig-release-1.19-blocking
+outputs:
+  - format: markdown
+    path: report.md
+  - format: json
+cache:
+  ttl:
+    testgrid: 1h
+`)
+	defer os.Remove(path)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned %v", err)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatalf("Validate returned %v", err)
+	}
+	if len(c.Dashboards) != 1 || c.Dashboards[0] != "sig-release-1.19-blocking" {
+		t.Errorf("Expected dashboards from file, got %v", c.Dashboards)
+	}
+	if len(c.Outputs) != 2 || c.Outputs[0].Path != "report.md" {
+		t.Errorf("Expected outputs from file, got %v", c.Outputs)
+	}
+	if c.Cache.Transport(CACHE_TESTGRID, nil).TTL != time.Hour ||
+		c.Cache.Transport(CACHE_GITHUB, nil).TTL != DefaultCacheTTL[CACHE_GITHUB] {
+		t.Errorf("Expected cache TTLs from file on top of the defaults, got %v", c.Cache.TTL)
+	}
+	if c.Store.Path != Default().Store.Path || len(c.Boards) != 1 {
+		t.Errorf("Expected defaults for keys not in file, got %+v", c)
+	}
+}
+
+// Tests that keys that are not part of the config are rejected
+func TestLoadUnknownKey(t *testing.T) {
+	path := writeConfig(t, "dashboard:\n  - typo\n")
+	defer os.Remove(path)
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "dashboard") {
+		t.Errorf("Expected error naming the unknown key, got %v", err)
+	}
+}
+
+// Tests that validation errors name the offending keys
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.Dashboards = append(c.Dashboards, "", c.Dashboards[0])
+	c.Boards[0].Columns["broken"] = -1
+	c.Boards = append(c.Boards, Board{Type: BOARD_PROJECTS_V2, OwnerType: "team"}, Board{Type: "beta"})
+	c.TestGrid.Record, c.TestGrid.Replay = "fixtures", "fixtures"
+	c.Outputs[0].Format = "xml"
+	c.Cache.TTL = map[string]time.Duration{"bigquery": time.Minute, CACHE_GITHUB: -time.Minute}
+	c.Cache.MaxAge = -time.Hour
+	c.Logging.Level = "chatty"
+
+	err := c.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	expected := []string{
+		"dashboards[2]", "dashboards[3]", "testgrid.replay", "boards[0].columns.broken",
+		"boards[1].owner", "boards[1].owner_type", "boards[1].number", "boards[2].type",
+		"outputs[0].format", "cache.ttl.bigquery", "cache.ttl.github", "cache.max_age", "logging.level",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for i, key := range expected {
+		if errs[i].Key != key {
+			t.Errorf("Expected error %d for %s, got %v", i, key, errs[i])
+		}
+	}
+}
+
+// Tests that boards are read by the reader of their type
+func TestBoardReader(t *testing.T) {
+	classic, ok := Default().Boards[0].Reader().(*rf.ClassicBoard)
+	if !ok || classic.ID != rf.CI_SIGNAL_BOARD_ID || classic.Columns[rf.CI_SIGNAL_OBSERVING_COL_ID] != "observing" {
+		t.Errorf("Expected the CI signal classic board, got %+v", classic)
+	}
+	b := Board{Type: BOARD_PROJECTS_V2, Owner: "kubernetes", OwnerType: OWNER_USER, Number: 68}
+	v2, ok := b.Reader().(*rf.ProjectV2Board)
+	if !ok || v2.Owner != "kubernetes" || !v2.User || v2.Number != 68 {
+		t.Errorf("Expected a projects-v2 board, got %+v", v2)
+	}
+}
@@ -0,0 +1,228 @@
+package diff
+
+// Compares two snapshots of CI status to show how flakes have changed between
+// collections, e.g. for the weekly Release Team Meeting update
+import (
+	"sort"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+const (
+	ISSUE_OPEN   string = rf.ISSUE_OPEN
+	ISSUE_CLOSED string = rf.ISSUE_CLOSED
+)
+
+// Report lists what changed between the Old and New snapshots
+type Report struct {
+	Old, New        time.Time
+	JobChanges      []JobChange
+	StartedFlaking  []TestChange
+	StoppedFlaking  []TestChange // flaking in Old, on a job neither flaking nor failing in New
+	StartedFailing  []TestChange // flaking in Old, on a failing job in New
+	StillFlaking    []TestChange
+	TestsUnknown    []UnknownJob    // flaking jobs whose tests are not compared
+	IssuesOpened    []rf.FlakeIssue // tracked in New but not Old, or reopened
+	IssuesClosed    []rf.FlakeIssue // closed in New, or no longer tracked
+	IssuesStillOpen []rf.FlakeIssue
+}
+
+// JobChange is a job whose status changed, From or To is empty if the job
+// was only on one of the snapshots
+type JobChange struct {
+	Dashboard string
+	Job       string
+	From, To  ci.OverallStatus
+}
+
+// TestChange is a flaking test on Job
+type TestChange struct {
+	Dashboard string
+	Job       string
+	Test      string
+	Sig       string
+}
+
+// UnknownJob is a flaking job whose tests could not be retrieved in one of
+// the snapshots, so its tests can not be compared
+type UnknownJob struct {
+	Dashboard  string
+	Job        string
+	FetchError string
+}
+
+// Compare reports the differences between the snapshots old and cur
+func Compare(old, cur *snapshot.Snapshot) *Report {
+	r := &Report{Old: old.CollectedAt, New: cur.CollectedAt}
+
+	oldJobs, newJobs := jobsByName(old.Collection), jobsByName(cur.Collection)
+	var names []string
+	for name := range oldJobs {
+		names = append(names, name)
+	}
+	for name := range newJobs {
+		names = append(names, name)
+	}
+	for _, name := range distinct(names) {
+		o, n := oldJobs[name], newJobs[name]
+		if o.OverallStatus == n.OverallStatus {
+			continue
+		}
+		dashboard := n.Dashboard
+		if dashboard == "" {
+			dashboard = o.Dashboard
+		}
+		r.JobChanges = append(r.JobChanges, JobChange{
+			Dashboard: dashboard,
+			Job:       name,
+			From:      o.OverallStatus,
+			To:        n.OverallStatus,
+		})
+	}
+
+	oldTests, oldUnknown := jobTests(old.Collection, ci.FLAKY)
+	newTests, newUnknown := jobTests(cur.Collection, ci.FLAKY)
+	failingTests, failingUnknown := jobTests(cur.Collection, ci.FAILING)
+	unknown := make(map[string]UnknownJob)
+	for name, u := range oldUnknown {
+		unknown[name] = u
+	}
+	for name, u := range newUnknown {
+		unknown[name] = u
+	}
+	for _, t := range oldTests {
+		if u, ok := failingUnknown[t.Job]; ok { // flaking job now failing
+			unknown[t.Job] = u
+		}
+	}
+	var unknownNames []string
+	for name := range unknown {
+		unknownNames = append(unknownNames, name)
+	}
+	for _, name := range distinct(unknownNames) {
+		r.TestsUnknown = append(r.TestsUnknown, unknown[name])
+	}
+
+	var testKeys []string
+	for key := range oldTests {
+		testKeys = append(testKeys, key)
+	}
+	for key := range newTests {
+		testKeys = append(testKeys, key)
+	}
+	for _, key := range distinct(testKeys) {
+		o, wasFlaking := oldTests[key]
+		n, isFlaking := newTests[key]
+		job := n.Job
+		if job == "" {
+			job = o.Job
+		}
+		if _, ok := unknown[job]; ok {
+			continue
+		}
+		_, isFailing := failingTests[key]
+		switch {
+		case isFlaking && wasFlaking:
+			r.StillFlaking = append(r.StillFlaking, n)
+		case isFlaking:
+			r.StartedFlaking = append(r.StartedFlaking, n)
+		case isFailing:
+			r.StartedFailing = append(r.StartedFailing, o)
+		default:
+			r.StoppedFlaking = append(r.StoppedFlaking, o)
+		}
+	}
+
+	oldIssues, newIssues := issuesByUrl(old.Issues), issuesByUrl(cur.Issues)
+	var urls []string
+	for url := range oldIssues {
+		urls = append(urls, url)
+	}
+	for url := range newIssues {
+		urls = append(urls, url)
+	}
+	for _, key := range distinct(urls) {
+		o, wasTracked := oldIssues[key]
+		n, isTracked := newIssues[key]
+		wasOpen := wasTracked && o.State != ISSUE_CLOSED
+		isOpen := isTracked && n.State != ISSUE_CLOSED
+		switch {
+		case isOpen && wasOpen:
+			r.IssuesStillOpen = append(r.IssuesStillOpen, n)
+		case isOpen:
+			r.IssuesOpened = append(r.IssuesOpened, n)
+		case wasOpen && isTracked:
+			r.IssuesClosed = append(r.IssuesClosed, n)
+		case wasOpen:
+			r.IssuesClosed = append(r.IssuesClosed, o)
+		}
+	}
+	return r
+}
+
+// jobsByName returns every job in c keyed by job name
+func jobsByName(c *ci.Collection) map[string]ci.JobStatus {
+	jobs := make(map[string]ci.JobStatus)
+	if c == nil {
+		return jobs
+	}
+	for _, cs := range c.Dashboards {
+		for _, statusJobs := range cs.Jobs {
+			for name, job := range statusJobs {
+				jobs[name] = job
+			}
+		}
+	}
+	return jobs
+}
+
+// jobTests returns the tests of each job in c with status keyed by job and
+// test name, and the jobs with status whose tests are unknown keyed by job name
+func jobTests(c *ci.Collection, status ci.OverallStatus) (map[string]TestChange, map[string]UnknownJob) {
+	tests := make(map[string]TestChange)
+	unknown := make(map[string]UnknownJob)
+	if c == nil {
+		return tests, unknown
+	}
+	for _, cs := range c.Dashboards {
+		for name, job := range cs.Jobs[status] {
+			if job.JobTestResults == nil || job.FetchError != "" {
+				unknown[name] = UnknownJob{Dashboard: job.Dashboard, Job: name, FetchError: job.FetchError}
+				continue
+			}
+			for _, test := range job.JobTestResults.Tests {
+				tests[name+"\x00"+test.Name] = TestChange{
+					Dashboard: job.Dashboard,
+					Job:       name,
+					Test:      test.Name,
+					Sig:       test.Sig,
+				}
+			}
+		}
+	}
+	return tests, unknown
+}
+
+// issuesByUrl returns issues keyed by their url
+func issuesByUrl(issues []rf.FlakeIssue) map[string]rf.FlakeIssue {
+	byUrl := make(map[string]rf.FlakeIssue)
+	for _, i := range issues {
+		byUrl[i.URL] = i
+	}
+	return byUrl
+}
+
+// distinct returns keys sorted with duplicates removed
+func distinct(keys []string) []string {
+	sort.Strings(keys)
+	var d []string
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			d = append(d, key)
+		}
+	}
+	return d
+}
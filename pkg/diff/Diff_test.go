@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// newSnapshot returns a snapshot of a single dashboard with the given jobs,
+// flaking and failing jobs have a test named after the job
+func newSnapshot(at time.Time, jobs map[string]ci.OverallStatus, issues []rf.FlakeIssue) *snapshot.Snapshot {
+	cs := &ci.CiStatus{Name: "dashboard", Jobs: make(map[ci.OverallStatus]map[string]ci.JobStatus)}
+	for name, status := range jobs {
+		job := ci.JobStatus{OverallStatus: status, Dashboard: cs.Name}
+		if status == ci.FLAKY || status == ci.FAILING {
+			job.JobTestResults = &ci.TestGridJobResult{Tests: []ci.TestResult{{Name: name + "-test"}}}
+		}
+		if cs.Jobs[status] == nil {
+			cs.Jobs[status] = make(map[string]ci.JobStatus)
+		}
+		cs.Jobs[status][name] = job
+	}
+	c := &ci.Collection{CollectedAt: at, Dashboards: []*ci.CiStatus{cs}}
+	return snapshot.New(c, issues)
+}
+
+// Tests that job status changes, test flakes and issues are compared
+func TestCompare(t *testing.T) {
+	lastWeek := time.Date(2020, time.October, 1, 9, 0, 0, 0, time.UTC)
+	old := newSnapshot(lastWeek, map[string]ci.OverallStatus{
+		"was-passing":  ci.PASSING,
+		"was-flaky":    ci.FLAKY,
+		"now-failing":  ci.FLAKY,
+		"still-flaky":  ci.FLAKY,
+		"still-broken": ci.FAILING,
+	}, []rf.FlakeIssue{
+		{LinkedIssue: ci.LinkedIssue{URL: "https://github.com/k/k/issues/1", State: "open"}},
+		{LinkedIssue: ci.LinkedIssue{URL: "https://github.com/k/k/issues/2", State: "open"}},
+	})
+	cur := newSnapshot(lastWeek.Add(7*24*time.Hour), map[string]ci.OverallStatus{
+		"was-passing":  ci.FLAKY,
+		"was-flaky":    ci.PASSING,
+		"now-failing":  ci.FAILING,
+		"still-flaky":  ci.FLAKY,
+		"still-broken": ci.FAILING,
+	}, []rf.FlakeIssue{
+		{LinkedIssue: ci.LinkedIssue{URL: "https://github.com/k/k/issues/1", State: "closed"}},
+		{LinkedIssue: ci.LinkedIssue{URL: "https://github.com/k/k/issues/2", State: "open"}},
+		{LinkedIssue: ci.LinkedIssue{URL: "https://github.com/k/k/issues/3", State: "open"}},
+	})
+
+	r := Compare(old, cur)
+
+	if len(r.JobChanges) != 3 ||
+		r.JobChanges[0].Job != "now-failing" || r.JobChanges[0].To != ci.FAILING ||
+		r.JobChanges[1].Job != "was-flaky" || r.JobChanges[1].To != ci.PASSING ||
+		r.JobChanges[2].Job != "was-passing" || r.JobChanges[2].From != ci.PASSING {
+		t.Errorf("Unexpected job changes %+v", r.JobChanges)
+	}
+	if len(r.StartedFlaking) != 1 || r.StartedFlaking[0].Job != "was-passing" {
+		t.Errorf("Unexpected tests started flaking %+v", r.StartedFlaking)
+	}
+	if len(r.StoppedFlaking) != 1 || r.StoppedFlaking[0].Job != "was-flaky" {
+		t.Errorf("Unexpected tests stopped flaking %+v", r.StoppedFlaking)
+	}
+	if len(r.StartedFailing) != 1 || r.StartedFailing[0].Job != "now-failing" {
+		t.Errorf("Unexpected tests started failing %+v", r.StartedFailing)
+	}
+	if len(r.StillFlaking) != 1 || r.StillFlaking[0].Job != "still-flaky" {
+		t.Errorf("Unexpected tests still flaking %+v", r.StillFlaking)
+	}
+	if len(r.IssuesOpened) != 1 || !strings.HasSuffix(r.IssuesOpened[0].URL, "/3") {
+		t.Errorf("Unexpected issues opened %+v", r.IssuesOpened)
+	}
+	if len(r.IssuesClosed) != 1 || !strings.HasSuffix(r.IssuesClosed[0].URL, "/1") {
+		t.Errorf("Unexpected issues closed %+v", r.IssuesClosed)
+	}
+
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write returned %v", err)
+	}
+	if !strings.Contains(b.String(), "`was-passing`: PASSING → FLAKY") {
+		t.Errorf("Expected job change in report:\n%s", b.String())
+	}
+}
+
+// Tests that the tests of a job whose test results could not be retrieved in
+// one of the snapshots are neither reported as started nor stopped flaking
+func TestCompareFetchError(t *testing.T) {
+	lastWeek := time.Date(2020, time.October, 1, 9, 0, 0, 0, time.UTC)
+	jobs := map[string]ci.OverallStatus{"flaky": ci.FLAKY, "other-flaky": ci.FLAKY}
+	old := newSnapshot(lastWeek, jobs, nil)
+	cur := newSnapshot(lastWeek.Add(7*24*time.Hour), jobs, nil)
+	flaky := cur.Collection.Dashboards[0].Jobs[ci.FLAKY]
+	flaky["flaky"] = ci.JobStatus{OverallStatus: ci.FLAKY, Dashboard: "dashboard", FetchError: "timed out"}
+
+	for _, r := range []*Report{Compare(old, cur), Compare(cur, old)} {
+		if len(r.StartedFlaking) != 0 || len(r.StoppedFlaking) != 0 || len(r.StillFlaking) != 1 {
+			t.Errorf("Expected only other-flaky to be compared, got %+v", r)
+		}
+		if len(r.TestsUnknown) != 1 || r.TestsUnknown[0].Job != "flaky" || r.TestsUnknown[0].FetchError != "timed out" {
+			t.Errorf("Expected flaky to be unknown, got %+v", r.TestsUnknown)
+		}
+	}
+}
+
+// Tests that the tests of a flaking job that started failing are reported as
+// failing rather than as stopped flaking, or as unknown if the failing job's
+// tests could not be retrieved
+func TestCompareStartedFailing(t *testing.T) {
+	lastWeek := time.Date(2020, time.October, 1, 9, 0, 0, 0, time.UTC)
+	old := newSnapshot(lastWeek, map[string]ci.OverallStatus{"a-job": ci.FLAKY, "b-job": ci.FLAKY}, nil)
+	cur := newSnapshot(lastWeek.Add(7*24*time.Hour), map[string]ci.OverallStatus{"a-job": ci.FAILING, "b-job": ci.FAILING}, nil)
+	failing := cur.Collection.Dashboards[0].Jobs[ci.FAILING]
+	failing["b-job"] = ci.JobStatus{OverallStatus: ci.FAILING, Dashboard: "dashboard", FetchError: "timed out"}
+
+	r := Compare(old, cur)
+	if len(r.StoppedFlaking) != 0 {
+		t.Errorf("Expected no tests to stop flaking, got %+v", r.StoppedFlaking)
+	}
+	if len(r.StartedFailing) != 1 || r.StartedFailing[0].Test != "a-job-test" {
+		t.Errorf("Expected a-job-test to start failing, got %+v", r.StartedFailing)
+	}
+	if len(r.TestsUnknown) != 1 || r.TestsUnknown[0].Job != "b-job" {
+		t.Errorf("Expected b-job to be unknown, got %+v", r.TestsUnknown)
+	}
+
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write returned %v", err)
+	}
+	if !strings.Contains(b.String(), "## Flaking tests whose job started failing (1)") {
+		t.Errorf("Expected failing tests in report:\n%s", b.String())
+	}
+}
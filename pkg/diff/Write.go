@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+// Write renders r as a Markdown update, suitable for pasting into the notes of
+// the weekly Release Team Meeting
+func (r *Report) Write(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# CI Signal changes %s to %s\n",
+		r.Old.Format(time.RFC1123), r.New.Format(time.RFC1123))
+
+	p.printf("\n## Jobs that changed status (%d)\n", len(r.JobChanges))
+	for _, c := range r.JobChanges {
+		p.printf("- %s `%s`: %s → %s\n", c.Dashboard, c.Job, statusOrNone(string(c.From)), statusOrNone(string(c.To)))
+	}
+
+	for _, section := range []struct {
+		title string
+		tests []TestChange
+	}{
+		{"Tests that started flaking", r.StartedFlaking},
+		{"Tests that stopped flaking", r.StoppedFlaking},
+		{"Flaking tests whose job started failing", r.StartedFailing},
+		{"Tests still flaking", r.StillFlaking},
+	} {
+		p.printf("\n## %s (%d)\n", section.title, len(section.tests))
+		for _, t := range section.tests {
+			p.printf("- %s `%s` %s %s\n", t.Dashboard, t.Job, t.Sig, t.Test)
+		}
+	}
+
+	p.printf("\n## Flaking jobs whose tests could not be compared (%d)\n", len(r.TestsUnknown))
+	for _, u := range r.TestsUnknown {
+		p.printf("- %s `%s`: %s\n", u.Dashboard, u.Job, reasonOrUnknown(u.FetchError))
+	}
+
+	for _, section := range []struct {
+		title  string
+		issues []rf.FlakeIssue
+	}{
+		{"Issues opened", r.IssuesOpened},
+		{"Issues closed", r.IssuesClosed},
+		{"Issues still open", r.IssuesStillOpen},
+	} {
+		p.printf("\n## %s (%d)\n", section.title, len(section.issues))
+		for _, i := range section.issues {
+			p.printf("- [%s#%d](%s) %s (`%s`)\n", i.Repo, i.Number, i.URL, i.Title, i.Job)
+		}
+	}
+	return p.err
+}
+
+// statusOrNone returns status, or "none" if the job had no status
+func statusOrNone(status string) string {
+	if status == "" {
+		return "none"
+	}
+	return status
+}
+
+// reasonOrUnknown returns the reason a job's tests could not be retrieved
+func reasonOrUnknown(fetchError string) string {
+	if fetchError == "" {
+		return "test results were not retrieved"
+	}
+	return fetchError
+}
+
+// printer remembers the first error writing to w so that it only needs
+// checking once
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
@@ -0,0 +1,163 @@
+package draft
+
+// Drafts flake issues, following the flake issue template, for the flaking
+// and failing tests that no issue tracks
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
+	"github.com/RobertKielty/flake-tracker/pkg/report"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+const (
+	HEADER_JOBS      string = "Which jobs are flaking:"
+	HEADER_REASON    string = "Reason for failure:"
+	HEADER_ANYTHING  string = "Anything else we need to know:"
+	FLAKE_LABEL      string = lint.FLAKE_LABEL
+	FAILING_LABEL    string = lint.FAILING_LABEL
+	MAX_MESSAGES     int    = 3   // failure messages quoted in a draft
+	MAX_MESSAGE_SIZE int    = 500 // bytes of each message quoted
+	MAX_SLUG_SIZE    int    = 100 // bytes of the job and test slug in a file name
+)
+
+var slugRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Draft is a flake issue ready to be filed
+type Draft struct {
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Labels    []string `json:"labels"`
+	Dashboard string   `json:"dashboard"`
+	Job       string   `json:"job"`
+	Test      string   `json:"test"`
+}
+
+// Untracked drafts an issue for each untracked test in snap, most severe first
+func Untracked(snap *snapshot.Snapshot) []Draft {
+	var drafts []Draft
+	for _, t := range report.Track(snap).Untracked {
+		drafts = append(drafts, New(t, messages(snap.Collection, t.Job, t.Test)))
+	}
+	return drafts
+}
+
+// New drafts an issue for t quoting reasons as the reason for failure
+func New(t report.TrackedTest, reasons []string) Draft {
+	kind, label := "Flaky", FLAKE_LABEL
+	if t.Status == ci.FAILING {
+		kind, label = "Failing", FAILING_LABEL
+	}
+	d := Draft{
+		Title:     fmt.Sprintf("[%s Test] %s", kind, t.Test),
+		Labels:    []string{label},
+		Dashboard: t.Dashboard,
+		Job:       t.Job,
+		Test:      t.Test,
+	}
+	if sig := lint.SigLabel(t.Sig); sig != "" {
+		d.Labels = append(d.Labels, sig)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n\n", HEADER_JOBS, t.Job)
+	fmt.Fprintf(&b, "%s\n%s\n\n", lint.HEADER_TESTS, t.Test)
+	fmt.Fprintf(&b, "%s\n%s\n\n", lint.HEADER_TESTGRID, ci.DashboardJobUrl(t.Dashboard, t.Job))
+	fmt.Fprintf(&b, "%s\n", HEADER_REASON)
+	for _, m := range reasons {
+		fmt.Fprintf(&b, "```\n%s\n```\n", m)
+	}
+	fmt.Fprintf(&b, "\n%s\n", HEADER_ANYTHING)
+	fmt.Fprintf(&b, "Flake rate %2.1f%% and failure rate %2.1f%% over the last %d runs",
+		t.Stats.FlakeRate*100, t.Stats.FailureRate*100, t.Stats.Runs)
+	if !t.Stats.LastFailure.IsZero() {
+		fmt.Fprintf(&b, ", last failed %s", t.Stats.LastFailure.Format(time.UnixDate))
+	}
+	fmt.Fprintf(&b, ".\n")
+	d.Body = b.String()
+	return d
+}
+
+// FileName returns a name for the file d is written to by Write. The slug of
+// the job and test is cut short, so a hash of them keeps the name unique.
+func (d Draft) FileName() string {
+	slug := strings.Trim(slugRE.ReplaceAllString(strings.ToLower(d.Job+"-"+d.Test), "-"), "-")
+	if len(slug) > MAX_SLUG_SIZE {
+		slug = strings.TrimRight(slug[:MAX_SLUG_SIZE], "-")
+	}
+	sum := sha256.Sum256([]byte(d.Job + "\x00" + d.Test))
+	return slug + "-" + hex.EncodeToString(sum[:4]) + ".md"
+}
+
+// Write writes each draft to its own file in dir, with its title and labels
+// ahead of its body, returning the paths written
+func Write(dir string, drafts []Draft) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, d := range drafts {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "Title: %s\nLabels: %s\n\n%s", d.Title, strings.Join(d.Labels, ", "), d.Body)
+		path := filepath.Join(dir, d.FileName())
+		if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// messages returns up to MAX_MESSAGES distinct failure messages of test on
+// job, most recent first
+func messages(c *ci.Collection, job, test string) []string {
+	j, found := c.Job(job)
+	if !found || j.JobTestResults == nil {
+		return nil
+	}
+	var msgs []string
+	seen := make(map[string]bool)
+	for _, t := range j.JobTestResults.Tests {
+		if t.Name != test {
+			continue
+		}
+		for _, m := range t.Messages {
+			m = strings.Replace(strings.TrimSpace(m), "```", "'''", -1) // keep the code block intact
+			if m == "" || seen[m] {
+				continue
+			}
+			seen[m] = true
+			if len(m) > MAX_MESSAGE_SIZE {
+				m = truncate(m, MAX_MESSAGE_SIZE) + "..."
+			}
+			msgs = append(msgs, m)
+			if len(msgs) == MAX_MESSAGES {
+				return msgs
+			}
+		}
+	}
+	return msgs
+}
+
+// truncate returns the first size bytes of s, or fewer so as not to split a
+// multi-byte rune
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
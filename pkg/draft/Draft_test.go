@@ -0,0 +1,123 @@
+package draft
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
+	"github.com/RobertKielty/flake-tracker/pkg/report"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	"github.com/google/go-github/github"
+)
+
+const testName = "[sig-node] Pods should be restarted"
+
+// testSnapshot has a flaking job with a single untracked test
+func testSnapshot() *snapshot.Snapshot {
+	results := &ci.TestGridJobResult{Tests: []ci.TestResult{{
+		Name:     testName,
+		Sig:      "[sig-node] ",
+		Messages: []string{"", "timed out waiting for pod", "timed out waiting for pod", "pod evicted"},
+		Stats:    ci.TestStats{Runs: 10, Flakes: 2, FlakeRate: 0.2},
+	}}}
+	cs := &ci.CiStatus{Name: "sig-release-master-blocking", Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{
+		ci.FLAKY: {"ci-kubernetes-e2e": {OverallStatus: ci.FLAKY, Dashboard: "sig-release-master-blocking", JobTestResults: results}},
+	}}
+	return snapshot.New(&ci.Collection{Dashboards: []*ci.CiStatus{cs}}, nil)
+}
+
+// Tests that drafts follow the flake issue template so that they pass the
+// linter once filed
+func TestUntracked(t *testing.T) {
+	drafts := Untracked(testSnapshot())
+	if len(drafts) != 1 {
+		t.Fatalf("Expected a draft for the untracked test, got %v", drafts)
+	}
+	d := drafts[0]
+	if d.Title != "[Flaky Test] "+testName {
+		t.Errorf("Unexpected title %q", d.Title)
+	}
+	findings := (&lint.Linter{}).Lint(lint.Issue{Body: d.Body, Labels: d.Labels})
+	if len(findings) != 0 {
+		t.Errorf("Expected draft to follow the template, got %v\n%s", findings, d.Body)
+	}
+	tests, _ := lint.ParseTests(d.Body)
+	if len(tests) != 1 || tests[0] != testName {
+		t.Errorf("Expected draft to name %q, got %v", testName, tests)
+	}
+	link := lint.FindTestGridLinks(d.Body)
+	if len(link) != 1 || link[0].Job != "ci-kubernetes-e2e" {
+		t.Errorf("Expected draft to link to the job, got %v", link)
+	}
+}
+
+// Tests that drafts are written to a file each
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drafts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := Write(dir, Untracked(testSnapshot()))
+	if err != nil || len(paths) != 1 || !strings.HasPrefix(filepath.Base(paths[0]), "ci-kubernetes-e2e-sig-node-pods-should-be-restarted-") {
+		t.Fatalf("Unexpected paths %v %v", paths, err)
+	}
+}
+
+// Tests that drafts for tests sharing a long prefix get different file names
+func TestFileName(t *testing.T) {
+	prefix := "[sig-storage] In-tree Volumes [Driver: local][LocalVolumeType: dir-link-bindmounted] " + strings.Repeat("[Testpattern: x] ", 10)
+	a := Draft{Job: "ci-kubernetes-e2e-gce", Test: prefix + "should mount"}
+	b := Draft{Job: "ci-kubernetes-e2e-gce", Test: prefix + "should unmount"}
+	if a.FileName() == b.FileName() || len(a.FileName()) > MAX_SLUG_SIZE+20 {
+		t.Errorf("Expected short distinct file names, got %s and %s", a.FileName(), b.FileName())
+	}
+}
+
+// Tests that messages are cut short without splitting a rune
+func TestTruncate(t *testing.T) {
+	if got := truncate("ab€", 3); got != "ab" {
+		t.Errorf("Expected the euro sign to be dropped whole, got %q", got)
+	}
+	if got := truncate("abc", 5); got != "abc" {
+		t.Errorf("Expected a short string to be kept, got %q", got)
+	}
+}
+
+// Tests that File creates an issue with the draft's title, body and labels
+func TestFile(t *testing.T) {
+	var got github.IssueRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/kubernetes/kubernetes/issues" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 1, "html_url": "https://github.com/kubernetes/kubernetes/issues/1"}`))
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	f := &Filer{Client: client, Owner: "kubernetes", Repo: "kubernetes"}
+
+	d := New(report.TrackedTest{Dashboard: "d", Status: ci.FAILING, Job: "j", Test: "t"}, nil)
+	u, err := f.File(context.Background(), d)
+	if err != nil || u != "https://github.com/kubernetes/kubernetes/issues/1" {
+		t.Fatalf("Unexpected url %q %v", u, err)
+	}
+	if got.GetTitle() != "[Failing Test] t" || got.GetBody() != d.Body || len(*got.Labels) != 1 || (*got.Labels)[0] != FAILING_LABEL {
+		t.Errorf("Unexpected issue request %+v", got)
+	}
+}
@@ -0,0 +1,28 @@
+package draft
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Filer files drafts as issues on a GitHub repository
+type Filer struct {
+	Client *github.Client
+	Owner  string
+	Repo   string
+}
+
+// File creates an issue from d, returning the URL of the new issue
+func (f *Filer) File(ctx context.Context, d Draft) (string, error) {
+	labels := d.Labels
+	issue, _, err := f.Client.Issues.Create(ctx, f.Owner, f.Repo, &github.IssueRequest{
+		Title:  &d.Title,
+		Body:   &d.Body,
+		Labels: &labels,
+	})
+	if err != nil {
+		return "", err
+	}
+	return issue.GetHTMLURL(), nil
+}
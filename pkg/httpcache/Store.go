@@ -0,0 +1,132 @@
+package httpcache
+
+// Keeps HTTP responses on disk, one JSON file per URL, so that they can be
+// revalidated or reused by later runs of the collector
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	entryExt  string = ".json"
+	tmpPrefix string = ".entry-"
+)
+
+// Entry is a response cached for URL
+type Entry struct {
+	URL      string
+	StoredAt time.Time
+	Status   int
+	Header   http.Header
+	Body     []byte
+}
+
+// Response returns the cached response to req
+func (e *Entry) Response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Store keeps entries in Dir, named by the hash of their URL
+type Store struct {
+	Dir string
+}
+
+// Get returns the entry cached for url, or nil if there is none
+func (s *Store) Get(url string) (*Entry, error) {
+	data, err := ioutil.ReadFile(s.path(url))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("Reading cached %s: %w", url, err)
+	}
+	if e.URL != url { // a hash collision, treat as not cached
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// Put caches e, replacing any entry for the same URL
+func (s *Store) Put(e *Entry) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed run never leaves a
+	// truncated entry behind
+	tmp, err := ioutil.TempFile(s.Dir, tmpPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(e.URL))
+}
+
+// Prune removes the entries stored before t, along with any temporary files
+// left behind by Put, returning how many files were removed. Entries are
+// otherwise kept forever, so without pruning the store grows with every URL
+// ever requested.
+func (s *Store) Prune(t time.Time) (int, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !(strings.HasSuffix(name, entryExt) || strings.HasPrefix(name, tmpPrefix)) {
+			continue
+		}
+		if !f.ModTime().Before(t) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(s.Dir, name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// path returns the file the entry for url is kept in
+func (s *Store) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+entryExt)
+}
@@ -0,0 +1,84 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests that a cached entry is returned as the response it was cached from,
+// and that URLs that were not cached are not found
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := &Store{Dir: dir}
+
+	url := "https://api.github.com/repos/kubernetes/kubernetes/issues/1"
+	if e, err := s.Get(url); e != nil || err != nil {
+		t.Fatalf("Expected nothing cached, got %+v %v", e, err)
+	}
+	err = s.Put(&Entry{
+		URL:      url,
+		StoredAt: time.Now(),
+		Status:   http.StatusOK,
+		Header:   http.Header{"Etag": []string{`"abc"`}},
+		Body:     []byte(`{"number": 1}`),
+	})
+	if err != nil {
+		t.Fatalf("Put returned %v", err)
+	}
+
+	e, err := s.Get(url)
+	if err != nil || e == nil {
+		t.Fatalf("Expected %s to be cached, got %v", url, err)
+	}
+	resp := e.Response(nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `"abc"` || string(body) != `{"number": 1}` {
+		t.Errorf("Unexpected cached response %+v %s", resp, body)
+	}
+	if e, _ := s.Get(url + "/comments"); e != nil {
+		t.Errorf("Expected other URLs not to be cached, got %+v", e)
+	}
+}
+
+// Tests that pruning removes the entries stored before a time and keeps the
+// others
+func TestStorePrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := &Store{Dir: dir}
+
+	old, recent := "https://testgrid.k8s.io/old/summary", "https://testgrid.k8s.io/recent/summary"
+	for _, url := range []string{old, recent} {
+		if err = s.Put(&Entry{URL: url, StoredAt: time.Now(), Status: http.StatusOK}); err != nil {
+			t.Fatalf("Put returned %v", err)
+		}
+	}
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	if err = os.Chtimes(s.path(old), weekAgo, weekAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 entry pruned, got %d %v", removed, err)
+	}
+	if e, _ := s.Get(old); e != nil {
+		t.Errorf("Expected %s to be pruned", old)
+	}
+	if e, _ := s.Get(recent); e == nil {
+		t.Errorf("Expected %s to be kept", recent)
+	}
+	if removed, err = (&Store{Dir: dir + "/missing"}).Prune(time.Now()); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to prune in a missing store, got %d %v", removed, err)
+	}
+}
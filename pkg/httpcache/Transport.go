@@ -0,0 +1,105 @@
+package httpcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Stats counts the GETs answered from the cache and those that were not
+type Stats struct {
+	Hits   int
+	Misses int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hit(s), %d miss(es)", s.Hits, s.Misses)
+}
+
+// Transport is an http.RoundTripper that answers GETs with the response in
+// Store while it is younger than TTL, caching every successful response it
+// fetches. Refresh bypasses the responses in Store, still caching new ones.
+// Responses that can not be cached are logged and returned all the same.
+type Transport struct {
+	Store     *Store
+	TTL       time.Duration
+	Refresh   bool
+	Transport http.RoundTripper // http.DefaultTransport when nil
+	Logger    *log.Logger
+
+	mu    sync.Mutex
+	stats Stats
+
+	now func() time.Time // replaced by tests
+}
+
+// RoundTrip answers req from the cache, or makes it with t.Transport
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.Method != "GET" {
+		return next.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	if !t.Refresh {
+		e, err := t.Store.Get(url)
+		if err == nil && e != nil && t.clock().Sub(e.StoredAt) < t.TTL {
+			t.count(true)
+			return e.Response(req), nil
+		}
+	}
+	t.count(false)
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{URL: url, StoredAt: t.clock(), Status: resp.StatusCode, Header: resp.Header, Body: body}
+	if err = t.Store.Put(e); err != nil {
+		t.logger().Warnf("Could not cache the response to %s %v", url, err)
+	}
+	return e.Response(req), nil
+}
+
+// Stats returns the hits and misses of the GETs made so far
+func (t *Transport) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+func (t *Transport) count(hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if hit {
+		t.stats.Hits++
+	} else {
+		t.stats.Misses++
+	}
+}
+
+func (t *Transport) logger() *log.Logger {
+	if t.Logger == nil {
+		return log.StandardLogger()
+	}
+	return t.Logger
+}
+
+func (t *Transport) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
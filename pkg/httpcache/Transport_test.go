@@ -0,0 +1,85 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Tests that GETs are answered from the cache until the TTL expires, or
+// unless the cache is refreshed, and that hits and misses are counted
+func TestTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"a-job":{"overall_status":"FLAKY"}}`))
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	transport := &Transport{Store: &Store{Dir: dir}, TTL: time.Minute, now: func() time.Time { return now }}
+	client := &http.Client{Transport: transport}
+	get := func() string {
+		resp, err := client.Get(srv.URL + "/sig-release-master-blocking/summary")
+		if err != nil {
+			t.Fatalf("Get returned %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if get() != get() || calls != 1 {
+		t.Errorf("Expected the second GET to be cached, made %d calls", calls)
+	}
+	now = now.Add(2 * time.Minute)
+	if get(); calls != 2 {
+		t.Errorf("Expected the cached response to expire, made %d calls", calls)
+	}
+	transport.Refresh = true
+	if get(); calls != 3 {
+		t.Errorf("Expected refresh to bypass the cache, made %d calls", calls)
+	}
+	if stats := transport.Stats(); stats.Hits != 1 || stats.Misses != 3 {
+		t.Errorf("Expected 1 hit and 3 misses, got %s", stats)
+	}
+}
+
+// Tests that a response that can not be cached is still returned
+func TestTransportPutFails(t *testing.T) {
+	f, err := ioutil.TempFile("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a-job":{"overall_status":"FLAKY"}}`))
+	}))
+	defer srv.Close()
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	// the store is a file, so entries can not be written in it
+	transport := &Transport{Store: &Store{Dir: f.Name()}, TTL: time.Minute, Logger: logger}
+	resp, err := (&http.Client{Transport: transport}).Get(srv.URL + "/sig-release-master-blocking/summary")
+	if err != nil {
+		t.Fatalf("Expected the fetched response, got %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != `{"a-job":{"overall_status":"FLAKY"}}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+}
@@ -0,0 +1,196 @@
+package lint
+
+// Checks GitHub flake issues against the flake issue template so that the
+// tracker, and Prow Robot, can link them to the jobs and tests they report
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity of a Finding, issues with ERROR findings can not be linked to
+// the tests they report
+type Severity string
+
+const (
+	ERROR   Severity = "error"
+	WARNING Severity = "warning"
+)
+
+// Rules reported in Finding.Rule
+const (
+	RULE_MISSING_TESTS_HEADER    string = "missing-tests-header"
+	RULE_MISSING_TESTGRID_HEADER string = "missing-testgrid-header"
+	RULE_HEADER_ORDER            string = "header-order"
+	RULE_NO_TESTS                string = "no-tests"
+	RULE_MISSING_TESTGRID_LINK   string = "missing-testgrid-link"
+	RULE_TESTGRID_LINK_NO_JOB    string = "testgrid-link-no-job"
+	RULE_JOB_NOT_FOUND           string = "job-not-found"
+	RULE_TEST_NOT_ON_JOB         string = "test-not-on-job"
+	RULE_MISSING_SIG_LABEL       string = "missing-sig-label"
+	RULE_MISSING_FLAKE_LABEL     string = "missing-flake-label"
+)
+
+const (
+	SIG_LABEL_PREFIX string = "sig/"
+	FLAKE_LABEL      string = "kind/flake"
+	FAILING_LABEL    string = "kind/failing-test"
+)
+
+// sigTestRE finds the SIG in a test name, e.g. node in "[sig-node] a test"
+var sigTestRE = regexp.MustCompile(`\[sig-([^\]]+)\]`)
+
+// SigLabel returns the GitHub label of the SIG named in a test, e.g.
+// sig/node for "[sig-node] a test", or "" if the test names no SIG
+func SigLabel(test string) string {
+	m := sigTestRE.FindStringSubmatch(test)
+	if m == nil {
+		return ""
+	}
+	return SIG_LABEL_PREFIX + m[1]
+}
+
+// Finding is a problem found in an issue and how to fix it
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s (fix: %s)", f.Severity, f.Rule, f.Message, f.Fix)
+}
+
+// Issue is the part of a GitHub issue that is linted
+type Issue struct {
+	Body   string
+	Labels []string
+}
+
+// Linter checks issues against the flake issue template. If JobTests is set
+// it is used to check that the tests an issue names are on its linked job.
+type Linter struct {
+	// JobTests returns the names of the tests of job on dashboard, found is
+	// false if the job is not known
+	JobTests func(dashboard, job string) (tests []string, found bool)
+}
+
+// Lint returns the findings for i, none if it follows the template
+func (l *Linter) Lint(i Issue) []Finding {
+	var findings []Finding
+	add := func(rule string, severity Severity, fix string, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Rule:     rule,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+			Fix:      fix,
+		})
+	}
+
+	tests, err := ParseTests(i.Body)
+	switch err {
+	case nil:
+		if len(tests) == 0 {
+			add(RULE_NO_TESTS, ERROR,
+				"List each flaking test on its own line under "+HEADER_TESTS,
+				"No tests are listed under %q", HEADER_TESTS)
+		}
+	case ErrMissingTestsHeader:
+		add(RULE_MISSING_TESTS_HEADER, ERROR,
+			"Add the line "+HEADER_TESTS+" followed by the names of the flaking tests",
+			"%v", err)
+	case ErrMissingTestGridHeader:
+		add(RULE_MISSING_TESTGRID_HEADER, ERROR,
+			"Add the line "+HEADER_TESTGRID+" after the flaking tests, followed by a link to the job on TestGrid",
+			"%v", err)
+	case ErrHeaderOrder:
+		add(RULE_HEADER_ORDER, ERROR,
+			"Move the flaking tests and the "+HEADER_TESTS+" line above the line "+HEADER_TESTGRID,
+			"%v", err)
+	default:
+		add(RULE_MISSING_TESTGRID_HEADER, ERROR,
+			"Follow the order of the flake issue template", "%v", err)
+	}
+
+	links := FindTestGridLinks(i.Body)
+	if len(links) == 0 {
+		add(RULE_MISSING_TESTGRID_LINK, ERROR,
+			"Add a link to the flaking job such as https://"+TESTGRID_HOST+"/<dashboard>#<job> under "+HEADER_TESTGRID,
+			"%v", ErrMissingTestGridLink)
+	}
+	for _, link := range links {
+		if link.Job == "" {
+			add(RULE_TESTGRID_LINK_NO_JOB, ERROR,
+				"Open the job on TestGrid and copy the link, it ends with #<job>",
+				"TestGrid link %s does not name a job", link.Url)
+			continue
+		}
+		if l.JobTests == nil {
+			continue
+		}
+		jobTests, found := l.JobTests(link.Dashboard, link.Job)
+		if !found {
+			add(RULE_JOB_NOT_FOUND, WARNING,
+				"Check the dashboard and job in the TestGrid link",
+				"Job %s was not found on dashboard %s", link.Job, link.Dashboard)
+			continue
+		}
+		for _, test := range tests {
+			if !containsTest(jobTests, test) {
+				add(RULE_TEST_NOT_ON_JOB, WARNING,
+					"Copy the test name exactly as TestGrid shows it, or close the issue if the test no longer flakes",
+					"Test %q is not among the failing tests of job %s", test, link.Job)
+			}
+		}
+	}
+
+	if !hasLabel(i.Labels, SIG_LABEL_PREFIX) {
+		add(RULE_MISSING_SIG_LABEL, WARNING,
+			"Add the /sig command for the SIG that owns the test, e.g. /sig node",
+			"Issue has no %s label", SIG_LABEL_PREFIX+"<sig>")
+	}
+	if !hasLabel(i.Labels, FLAKE_LABEL) && !hasLabel(i.Labels, FAILING_LABEL) {
+		add(RULE_MISSING_FLAKE_LABEL, WARNING,
+			"Add the /kind flake command, or /kind failing-test if the test fails every run",
+			"Issue has neither the %s nor the %s label", FLAKE_LABEL, FAILING_LABEL)
+	}
+	return findings
+}
+
+// HasErrors returns true if any of findings has ERROR severity
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+// containsTest returns true if test is one of tests, allowing for the issue
+// leaving out the leading [...] tags of the test, e.g. its [sig-...] prefix
+func containsTest(tests []string, test string) bool {
+	test = strings.TrimSpace(test)
+	for _, t := range tests {
+		if t == test {
+			return true
+		}
+		for i, c := range t {
+			if c == ']' && strings.TrimSpace(t[i+1:]) == test {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasLabel returns true if a label in labels starts with prefix
+func hasLabel(labels []string, prefix string) bool {
+	for _, label := range labels {
+		if strings.HasPrefix(label, prefix) {
+			return true
+		}
+	}
+	return false
+}
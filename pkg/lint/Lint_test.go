@@ -0,0 +1,97 @@
+package lint
+
+import "testing"
+
+const goodIssue = `Which test(s) are flaking:
+[sig-instrumentation] MetricsGrabber should grab all metrics from a Scheduler
+
+Testgrid link:
+https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default&width=5
+
+Reason for failure:
+timed out
+`
+
+// jobTests has a single job with a single test
+func jobTests(dashboard, job string) ([]string, bool) {
+	if dashboard == "sig-release-master-blocking" && job == "gce-cos-master-default" {
+		return []string{"[sig-instrumentation] MetricsGrabber should grab all metrics from a Scheduler"}, true
+	}
+	return nil, false
+}
+
+// Tests that link parsing copes with TestGrid query parameters
+func TestParseTestGridLink(t *testing.T) {
+	link, err := ParseTestGridLink("https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default&width=5")
+	if err != nil || link.Dashboard != "sig-release-master-blocking" || link.Job != "gce-cos-master-default" {
+		t.Errorf("Unexpected link %+v %v", link, err)
+	}
+}
+
+// Tests the rules triggered by issues that do not follow the template
+func TestLint(t *testing.T) {
+	labels := []string{"sig/instrumentation", "kind/flake"}
+	scenarios := []struct {
+		name   string
+		issue  Issue
+		rules  []string
+		errors bool
+	}{
+		{"follows template", Issue{goodIssue, labels}, nil, false},
+		{"missing labels", Issue{goodIssue, nil},
+			[]string{RULE_MISSING_SIG_LABEL, RULE_MISSING_FLAKE_LABEL}, false},
+		{"missing tests header", Issue{"Testgrid link:\nhttps://testgrid.k8s.io/d#j\n", labels},
+			[]string{RULE_MISSING_TESTS_HEADER, RULE_JOB_NOT_FOUND}, true},
+		{"missing testgrid header", Issue{"Which test(s) are flaking:\nfoo\n", labels},
+			[]string{RULE_MISSING_TESTGRID_HEADER, RULE_MISSING_TESTGRID_LINK}, true},
+		{"headers out of order", Issue{"Testgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default\nWhich test(s) are flaking:\nfoo\n", labels},
+			[]string{RULE_HEADER_ORDER}, true},
+		{"no tests", Issue{"Which test(s) are flaking:\n\nTestgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default\n", labels},
+			[]string{RULE_NO_TESTS}, true},
+		{"link with no job", Issue{"Which test(s) are flaking:\nfoo\nTestgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking\n", labels},
+			[]string{RULE_TESTGRID_LINK_NO_JOB}, true},
+		{"test not on job", Issue{"Which test(s) are flaking:\nsomething else\nTestgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default\n", labels},
+			[]string{RULE_TEST_NOT_ON_JOB}, false},
+		{"test without its sig prefix", Issue{"Which test(s) are flaking:\nMetricsGrabber should grab all metrics from a Scheduler\nTestgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default\n", labels},
+			nil, false},
+		{"part of a test name", Issue{"Which test(s) are flaking:\na\nTestgrid link:\nhttps://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default\n", labels},
+			[]string{RULE_TEST_NOT_ON_JOB}, false},
+	}
+
+	l := &Linter{JobTests: jobTests}
+	for _, s := range scenarios {
+		findings := l.Lint(s.issue)
+		if len(findings) != len(s.rules) {
+			t.Errorf("%s: expected rules %v, got %v", s.name, s.rules, findings)
+			continue
+		}
+		for i, rule := range s.rules {
+			if findings[i].Rule != rule || findings[i].Fix == "" {
+				t.Errorf("%s: expected finding %d to be %s with a fix, got %v", s.name, i, rule, findings[i])
+			}
+		}
+		if HasErrors(findings) != s.errors {
+			t.Errorf("%s: expected HasErrors to be %v, got %v", s.name, s.errors, findings)
+		}
+	}
+}
+
+// Tests that tests are found in comments without a Testgrid link: header
+func TestFindTests(t *testing.T) {
+	tests := FindTests("Also seen\r\nWhich test(s) are flaking:\r\n\r\nfoo\r\nbar\r\n\r\nThanks")
+	if len(tests) != 2 || tests[0] != "foo" || tests[1] != "bar" {
+		t.Errorf("Expected foo and bar, got %q", tests)
+	}
+	if tests = FindTests(goodIssue); len(tests) != 1 {
+		t.Errorf("Expected the test of a templated issue, got %q", tests)
+	}
+}
+
+// Tests that SIG labels are derived from the SIG named in a test
+func TestSigLabel(t *testing.T) {
+	for test, label := range map[string]string{"[sig-node] ": "sig/node", "[sig-api-machinery] a test": "sig/api-machinery", "job-owner": ""} {
+		if got := SigLabel(test); got != label {
+			t.Errorf("SigLabel(%q) = %q, expected %q", test, got, label)
+		}
+	}
+}
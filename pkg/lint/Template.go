@@ -0,0 +1,122 @@
+package lint
+
+// Parses the sections of the GitHub flake issue template
+import (
+	"bufio"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	HEADER_TESTS    string = "Which test(s) are flaking:"
+	HEADER_TESTGRID string = "Testgrid link:"
+	TESTGRID_HOST   string = "testgrid.k8s.io"
+)
+
+var (
+	ErrMissingTestsHeader    = errors.New("Missing header " + HEADER_TESTS)
+	ErrMissingTestGridHeader = errors.New("Missing header " + HEADER_TESTGRID)
+	ErrMissingTestGridLink   = errors.New("No " + TESTGRID_HOST + " link found")
+	ErrHeaderOrder           = errors.New("Header " + HEADER_TESTGRID + " comes before " + HEADER_TESTS)
+
+	tgUrlRE = regexp.MustCompile(`https://testgrid\.k8s\.io/[^\s)\]>"']+`)
+)
+
+// TestGridLink is a link to a job on a TestGrid dashboard
+type TestGridLink struct {
+	Url       string
+	Dashboard string
+	Job       string // empty if the link has no #job fragment
+}
+
+// ParseTests collects tests referenced in the body of a formatted Flake Issue on GitHub
+// Each non-empty line between "Which test(s) are flaking:" and "Testgrid link:"
+// is considered to be a test
+func ParseTests(b string) ([]string, error) {
+	var tests []string
+
+	start := strings.Index(b, HEADER_TESTS)
+	if start == -1 {
+		return nil, ErrMissingTestsHeader
+	}
+	start += len(HEADER_TESTS)
+
+	end := strings.Index(b, HEADER_TESTGRID)
+	if end == -1 {
+		return nil, ErrMissingTestGridHeader
+	}
+	if end < start {
+		return nil, ErrHeaderOrder
+	}
+
+	// Start reading after "Which test(s) are flaking:"
+	s := bufio.NewScanner(strings.NewReader(b[start:end]))
+	for s.Scan() {
+		t := strings.TrimSpace(s.Text())
+		if t != "" {
+			tests = append(tests, t)
+		}
+	}
+	return tests, nil
+}
+
+// FindTests returns the tests listed under "Which test(s) are flaking:" like
+// ParseTests, but where there is no "Testgrid link:" header, as in a comment
+// that only names more tests, the list ends at the first blank line
+func FindTests(b string) []string {
+	if tests, err := ParseTests(b); err == nil {
+		return tests
+	}
+	start := strings.Index(b, HEADER_TESTS)
+	if start == -1 {
+		return nil
+	}
+
+	var tests []string
+	s := bufio.NewScanner(strings.NewReader(b[start+len(HEADER_TESTS):]))
+	for s.Scan() {
+		t := strings.TrimSpace(s.Text())
+		if t == "" {
+			if len(tests) > 0 {
+				break
+			}
+			continue
+		}
+		tests = append(tests, t)
+	}
+	return tests
+}
+
+// FindTestGridLinks returns every TestGrid link in b in the order they appear
+func FindTestGridLinks(b string) []TestGridLink {
+	var links []TestGridLink
+	for _, u := range tgUrlRE.FindAllString(b, -1) {
+		if link, err := ParseTestGridLink(u); err == nil {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// ParseTestGridLink extracts the dashboard and job from a TestGrid url such as
+// https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default&width=5
+func ParseTestGridLink(link string) (TestGridLink, error) {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil {
+		return TestGridLink{}, err
+	}
+	if u.Host != TESTGRID_HOST {
+		return TestGridLink{}, ErrMissingTestGridLink
+	}
+
+	tg := TestGridLink{Url: link, Dashboard: strings.Trim(u.Path, "/")}
+	if i := strings.Index(u.Fragment, "&"); i >= 0 {
+		tg.Job = u.Fragment[:i]
+	} else {
+		tg.Job = u.Fragment
+	}
+	return tg, nil
+}
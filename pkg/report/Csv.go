@@ -0,0 +1,26 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// CsvReporter writes an RFC 4180 CSV file with a header line and one line per
+// Row, every line has the same columns
+type CsvReporter struct{}
+
+func (r *CsvReporter) Report(w io.Writer, snap *snapshot.Snapshot) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Header); err != nil {
+		return err
+	}
+	for _, row := range Rows(snap) {
+		if err := cw.Write(row.Columns()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+const (
+	NO_SIG string = "job-owner" // SIG of issues with no sig label or [sig-...] test
+)
+
+// EffortCount is the number of flake issues of a job or SIG at each Effort
+type EffortCount struct {
+	Name   string            `json:"name"`
+	Counts map[rf.Effort]int `json:"counts"`
+	Total  int               `json:"total"`
+}
+
+// EffortDistribution is the effort on flake issues per job and per SIG,
+// ordered by name
+type EffortDistribution struct {
+	ByJob []EffortCount `json:"by_job"`
+	BySig []EffortCount `json:"by_sig"`
+}
+
+// DistributeEffort counts issues by Effort per job and per SIG, an issue
+// counts towards each SIG it is labelled with
+func DistributeEffort(issues []rf.FlakeIssue) EffortDistribution {
+	byJob := make(map[string]*EffortCount)
+	bySig := make(map[string]*EffortCount)
+	count := func(counts map[string]*EffortCount, name string, effort rf.Effort) {
+		ec, ok := counts[name]
+		if !ok {
+			ec = &EffortCount{Name: name, Counts: make(map[rf.Effort]int)}
+			counts[name] = ec
+		}
+		ec.Counts[effort]++
+		ec.Total++
+	}
+
+	for _, i := range issues {
+		effort := i.Effort()
+		count(byJob, i.Job, effort)
+		for _, sig := range issueSigs(i) {
+			count(bySig, sig, effort)
+		}
+	}
+	return EffortDistribution{ByJob: sortedEffortCounts(byJob), BySig: sortedEffortCounts(bySig)}
+}
+
+// issueSigs returns the SIGs of i from its sig/ labels, or from the names of
+// the tests it reports if it has none
+func issueSigs(i rf.FlakeIssue) []string {
+	var sigs []string
+	seen := make(map[string]bool)
+	add := func(sig string) {
+		if !seen[sig] {
+			seen[sig] = true
+			sigs = append(sigs, sig)
+		}
+	}
+	for _, l := range i.Labels {
+		if strings.HasPrefix(l, lint.SIG_LABEL_PREFIX) {
+			add(l)
+		}
+	}
+	if len(sigs) == 0 {
+		for _, t := range i.Tests {
+			if sig := lint.SigLabel(t); sig != "" {
+				add(sig)
+			}
+		}
+	}
+	if len(sigs) == 0 {
+		add(NO_SIG)
+	}
+	return sigs
+}
+
+// sortedEffortCounts returns the effort counts ordered by name
+func sortedEffortCounts(counts map[string]*EffortCount) []EffortCount {
+	sorted := make([]EffortCount, 0, len(counts))
+	for _, ec := range counts {
+		sorted = append(sorted, *ec)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
@@ -0,0 +1,30 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// JsonReporter writes the summary and rows of the report as a JSON document
+type JsonReporter struct{}
+
+// jsonReport is the document written by JsonReporter
+type jsonReport struct {
+	Summary   Summary            `json:"summary"`
+	Untracked []TrackedTest      `json:"untracked"`
+	Effort    EffortDistribution `json:"effort"`
+	Rows      []Row              `json:"rows"`
+}
+
+func (r *JsonReporter) Report(w io.Writer, snap *snapshot.Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonReport{
+		Summary:   Summarise(snap),
+		Untracked: Track(snap).Untracked,
+		Effort:    DistributeEffort(snap.Issues),
+		Rows:      Rows(snap),
+	})
+}
@@ -0,0 +1,204 @@
+package report
+
+// Renders a snapshot of collected CI status in one of several output formats
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+const (
+	FORMAT_CSV      string = "csv"
+	FORMAT_JSON     string = "json"
+	FORMAT_MARKDOWN string = "markdown"
+	FORMAT_ORG      string = "org"
+
+	UNKNOWN_TESTS string = "unknown" // reported for jobs whose tests could not be retrieved
+)
+
+// Formats lists the output formats New accepts
+var Formats = []string{FORMAT_CSV, FORMAT_JSON, FORMAT_MARKDOWN, FORMAT_ORG}
+
+// Reporter writes a report on a snapshot to w
+type Reporter interface {
+	Report(w io.Writer, snap *snapshot.Snapshot) error
+}
+
+// New returns the Reporter for the named output format
+func New(format string) (Reporter, error) {
+	switch format {
+	case FORMAT_CSV:
+		return &CsvReporter{}, nil
+	case FORMAT_JSON:
+		return &JsonReporter{}, nil
+	case FORMAT_MARKDOWN, "md":
+		return &TableReporter{Style: MarkdownStyle}, nil
+	case FORMAT_ORG:
+		return &TableReporter{Style: OrgStyle}, nil
+	}
+	return nil, fmt.Errorf("Unknown output format %q, expected one of %v", format, Formats)
+}
+
+// Row is a line of the report: a test of a flaking or failing job, once per
+// bug linked to the test, or a job with any other status
+type Row struct {
+	CollectedAt time.Time        `json:"collected_at"`
+	Dashboard   string           `json:"dashboard"`
+	Status      ci.OverallStatus `json:"status"`
+	Job         string           `json:"job"`
+	TestIndex   int              `json:"test_index,omitempty"` // 1 based rank of the test by severity
+	TestCount   int              `json:"test_count,omitempty"`
+	Test        string           `json:"test,omitempty"`
+	Sig         string           `json:"sig,omitempty"`
+	Stats       *ci.TestStats    `json:"stats,omitempty"`
+	Url         string           `json:"url"`
+	LinkedBug   *ci.LinkedIssue  `json:"linked_bug,omitempty"`
+	Tracked     *bool            `json:"tracked,omitempty"` // set on test rows, see Track
+	Note        string           `json:"note,omitempty"`
+}
+
+// Header names the columns of tabular reports, see Row.Columns
+var Header = []string{
+	"Collected At", "Dashboard", "Status", "Job", "Test", "Test Name", "Sig",
+	"Flake Rate %", "Failure Rate %", "Runs", "Last Failure", "Url", "Linked Bug", "Tracked", "Note",
+}
+
+// Columns returns the values of r in the order of Header
+func (r Row) Columns() []string {
+	var test, flakeRate, failureRate, runs, lastFailure, linkedBug, tracked string
+	if r.LinkedBug != nil {
+		linkedBug = r.LinkedBug.URL
+	}
+	if r.Tracked != nil {
+		tracked = "no"
+		if *r.Tracked {
+			tracked = "yes"
+		}
+	}
+	if r.TestCount > 0 {
+		test = fmt.Sprintf("%d of %d", r.TestIndex, r.TestCount)
+	}
+	if r.Stats != nil {
+		flakeRate = fmt.Sprintf("%2.1f", r.Stats.FlakeRate*100)
+		failureRate = fmt.Sprintf("%2.1f", r.Stats.FailureRate*100)
+		runs = strconv.Itoa(r.Stats.Runs)
+		if !r.Stats.LastFailure.IsZero() {
+			lastFailure = r.Stats.LastFailure.Format(time.UnixDate)
+		}
+	}
+	return []string{
+		r.CollectedAt.Format(time.UnixDate), r.Dashboard, string(r.Status), r.Job,
+		test, r.Test, r.Sig, flakeRate, failureRate, runs, lastFailure, r.Url, linkedBug, tracked, r.Note,
+	}
+}
+
+// Rows returns the rows of the report on snap, ordered by dashboard, status
+// (see ci.KnownStatuses), job name and test severity
+func Rows(snap *snapshot.Snapshot) []Row {
+	var rows []Row
+	for _, cs := range snap.Collection.Dashboards {
+		for _, status := range cs.Statuses() {
+			jobs := cs.Jobs[status]
+			for _, jobName := range sortedJobNames(jobs) {
+				rows = append(rows, jobRows(snap.CollectedAt, cs.Name, jobName, jobs[jobName], snap.Issues)...)
+			}
+		}
+	}
+	return rows
+}
+
+// jobRows returns the rows reporting on a single job, marking its tests as
+// tracked if one of issues is open and tracks them
+func jobRows(collectedAt time.Time, dashboard, jobName string, job ci.JobStatus, issues []rf.FlakeIssue) []Row {
+	row := Row{
+		CollectedAt: collectedAt,
+		Dashboard:   dashboard,
+		Status:      job.OverallStatus,
+		Job:         jobName,
+		Url:         job.Url,
+	}
+	if job.OverallStatus != ci.FLAKY && job.OverallStatus != ci.FAILING {
+		return []Row{row}
+	}
+
+	results := job.JobTestResults
+	if results == nil {
+		row.Test = UNKNOWN_TESTS
+		row.Note = job.FetchError
+		return []Row{row}
+	}
+
+	var rows []Row
+	for i, test := range results.Tests {
+		testRow := row
+		testRow.TestIndex = i + 1
+		testRow.TestCount = len(results.Tests)
+		testRow.Test = test.Name
+		testRow.Sig = test.Sig
+		stats := test.Stats
+		testRow.Stats = &stats
+		tracked := hasOpenIssue(test.LinkedBugs) || len(openIssuesFor(issues, jobName, test.Name)) > 0
+		testRow.Tracked = &tracked
+		if len(test.LinkedBugs) == 0 {
+			rows = append(rows, testRow)
+			continue
+		}
+		for k := range test.LinkedBugs {
+			bugRow := testRow
+			bugRow.LinkedBug = &test.LinkedBugs[k]
+			rows = append(rows, bugRow)
+		}
+	}
+	return rows
+}
+
+// Summary counts the jobs in a snapshot by status
+type Summary struct {
+	CollectedAt time.Time     `json:"collected_at"`
+	Total       int           `json:"total"`
+	Statuses    []StatusCount `json:"statuses"`
+}
+
+// StatusCount is the number and percentage of jobs with Status
+type StatusCount struct {
+	Status  ci.OverallStatus `json:"status"`
+	Count   int              `json:"count"`
+	Percent float64          `json:"percent"`
+}
+
+// Summarise counts the jobs in snap by status, the percentages of every status
+// add up to 100
+func Summarise(snap *snapshot.Snapshot) Summary {
+	s := Summary{CollectedAt: snap.CollectedAt}
+	counts := make(map[ci.OverallStatus]int)
+	for _, cs := range snap.Collection.Dashboards {
+		for status, jobs := range cs.Jobs {
+			counts[status] += len(jobs)
+			s.Total += len(jobs)
+		}
+	}
+	for _, status := range snap.Collection.Statuses() {
+		sc := StatusCount{Status: status, Count: counts[status]}
+		if s.Total > 0 {
+			sc.Percent = float64(sc.Count) / float64(s.Total) * 100
+		}
+		s.Statuses = append(s.Statuses, sc)
+	}
+	return s
+}
+
+// sortedJobNames returns the names of jobs in alphabetical order
+func sortedJobNames(jobs map[string]ci.JobStatus) []string {
+	names := make([]string, 0, len(jobs))
+	for jobName := range jobs {
+		names = append(names, jobName)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// testSnapshot has a flaking job with two tests, one with a linked bug, a
+// failing job whose tests could not be retrieved and a passing job
+func testSnapshot() *snapshot.Snapshot {
+	flaky := &ci.TestGridJobResult{Tests: []ci.TestResult{
+		{Name: "[sig-node] a test, with a comma", Sig: "[sig-node] ",
+			LinkedBugs: []ci.LinkedIssue{{Number: 1, URL: "https://github.com/kubernetes/kubernetes/issues/1", State: ci.ISSUE_OPEN}}},
+		{Name: `[sig-apps] a "quoted" | piped test`, Sig: "[sig-apps] "},
+	}}
+	cs := &ci.CiStatus{Name: "sig-release-master-blocking", Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{
+		ci.FLAKY:   {"flaky-job": {OverallStatus: ci.FLAKY, JobTestResults: flaky}},
+		ci.FAILING: {"failing-job": {OverallStatus: ci.FAILING, FetchError: "timed out"}},
+		ci.PASSING: {"passing-job": {OverallStatus: ci.PASSING}},
+	}}
+	at := time.Date(2020, time.October, 1, 9, 0, 0, 0, time.UTC)
+	return snapshot.New(&ci.Collection{CollectedAt: at, Dashboards: []*ci.CiStatus{cs}}, nil)
+}
+
+// Tests that the CSV report has a header and the same columns on every line
+func TestCsvReporter(t *testing.T) {
+	var b bytes.Buffer
+	if err := (&CsvReporter{}).Report(&b, testSnapshot()); err != nil {
+		t.Fatalf("Report returned %v", err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("Report is not valid CSV: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Expected header and 4 rows, got %d: %v", len(records), records)
+	}
+	if records[0][0] != Header[0] {
+		t.Errorf("Expected header, got %v", records[0])
+	}
+	if records[1][3] != "failing-job" || records[1][5] != UNKNOWN_TESTS || records[1][14] != "timed out" {
+		t.Errorf("Expected failing job of unknown tests first, got %v", records[1])
+	}
+	if records[2][5] != "[sig-node] a test, with a comma" || records[2][12] == "" || records[2][13] != "yes" {
+		t.Errorf("Expected test with linked bug, got %v", records[2])
+	}
+	if records[3][5] != `[sig-apps] a "quoted" | piped test` {
+		t.Errorf("Expected quoted test name to survive, got %v", records[3])
+	}
+}
+
+// Tests that the JSON report summary adds up to 100%
+func TestJsonReporter(t *testing.T) {
+	var b bytes.Buffer
+	if err := (&JsonReporter{}).Report(&b, testSnapshot()); err != nil {
+		t.Fatalf("Report returned %v", err)
+	}
+	var r jsonReport
+	if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	percent := 0.0
+	for _, sc := range r.Summary.Statuses {
+		percent += sc.Percent
+	}
+	if r.Summary.Total != 3 || percent < 99.9 || len(r.Rows) != 4 {
+		t.Errorf("Unexpected summary %+v and %d rows", r.Summary, len(r.Rows))
+	}
+	if bug := r.Rows[1].LinkedBug; bug == nil || bug.Number != 1 || bug.State != ci.ISSUE_OPEN {
+		t.Errorf("Expected the linked issue of the test, got %+v", r.Rows[1])
+	}
+}
+
+// Tests that Markdown and Org tables have a line per row and escape pipes
+func TestTableReporters(t *testing.T) {
+	for format, pipe := range map[string]string{FORMAT_MARKDOWN: `\|`, FORMAT_ORG: `\vert{}`} {
+		reporter, err := New(format)
+		if err != nil {
+			t.Fatalf("New(%s) returned %v", format, err)
+		}
+		var b bytes.Buffer
+		if err := reporter.Report(&b, testSnapshot()); err != nil {
+			t.Fatalf("%s Report returned %v", format, err)
+		}
+		lines := 0
+		for _, line := range strings.Split(b.String(), "\n") {
+			if strings.HasPrefix(line, "| ") {
+				lines++
+			}
+		}
+		// summary header, 3 statuses and total, untracked header and 1 untracked
+		// test, 2 effort headers, rows header and 4 rows
+		if lines != 14 {
+			t.Errorf("%s: expected 14 table lines, got %d\n%s", format, lines, b.String())
+		}
+		if !strings.Contains(b.String(), pipe+" piped test") {
+			t.Errorf("%s: expected | to be escaped as %s\n%s", format, pipe, b.String())
+		}
+	}
+}
+
+// Tests that unknown formats are rejected
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New("xml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+// Tests that tests are tracked by open issues naming them, or naming no
+// tests on their job, and that untracked tests are ranked by severity
+func TestTrack(t *testing.T) {
+	snap := testSnapshot()
+	tests := snap.Collection.Dashboards[0].Jobs[ci.FLAKY]["flaky-job"].JobTestResults.Tests
+	tests[0].LinkedBugs = nil
+	tests[0].Stats.FlakeRate = 0.1
+	tests[1].Stats.FlakeRate = 0.2
+
+	tracking := Track(snap)
+	if len(tracking.Tracked) != 0 || len(tracking.Untracked) != 2 || tracking.Untracked[0].Test != tests[1].Name {
+		t.Errorf("Expected two untracked tests, most flaky first, got %+v", tracking)
+	}
+
+	snap.Issues = []rf.FlakeIssue{
+		{LinkedIssue: ci.LinkedIssue{Number: 1, State: rf.ISSUE_OPEN}, Job: "flaky-job", Tests: []string{"a test, with a comma"}},
+		{LinkedIssue: ci.LinkedIssue{Number: 2, State: rf.ISSUE_CLOSED}, Job: "flaky-job"},
+	}
+	tracking = Track(snap)
+	if len(tracking.Tracked) != 1 || tracking.Tracked[0].Issues[0].Number != 1 || len(tracking.Untracked) != 1 {
+		t.Errorf("Expected test named by open issue to be tracked, got %+v", tracking)
+	}
+
+	snap.Issues[1].State = rf.ISSUE_OPEN
+	if tracking = Track(snap); len(tracking.Untracked) != 0 {
+		t.Errorf("Expected open issue on the job to track all its tests, got %+v", tracking)
+	}
+}
+
+// Tests that effort is counted per job and per SIG, falling back to the SIG
+// of the tests an issue reports when it has no sig label
+func TestDistributeEffort(t *testing.T) {
+	issues := []rf.FlakeIssue{
+		{Job: "a", LinkedIssue: ci.LinkedIssue{Column: "New (no response yet)", Labels: []string{"sig/node", "sig/apps"}}},
+		{Job: "a", LinkedIssue: ci.LinkedIssue{State: rf.ISSUE_CLOSED}, Tests: []string{"[sig-node] a test"}},
+		{Job: "b", LinkedIssue: ci.LinkedIssue{Column: "Under investigation"}, PullRequests: []rf.PullRequest{{Number: 2, State: rf.ISSUE_OPEN}}},
+	}
+	d := DistributeEffort(issues)
+	if len(d.ByJob) != 2 || d.ByJob[0].Name != "a" || d.ByJob[0].Total != 2 ||
+		d.ByJob[0].Counts[rf.EFFORT_AWAITING_RESPONSE] != 1 || d.ByJob[0].Counts[rf.EFFORT_FIXED] != 1 ||
+		d.ByJob[1].Counts[rf.EFFORT_PR_SUBMITTED] != 1 {
+		t.Errorf("Unexpected effort by job %+v", d.ByJob)
+	}
+	sigs := make(map[string]int)
+	for _, ec := range d.BySig {
+		sigs[ec.Name] = ec.Total
+	}
+	if len(sigs) != 3 || sigs["sig/node"] != 2 || sigs["sig/apps"] != 1 || sigs[NO_SIG] != 1 {
+		t.Errorf("Unexpected effort by SIG %+v", d.BySig)
+	}
+}
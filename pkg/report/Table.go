@@ -0,0 +1,128 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// TableStyle is the markup used by a TableReporter
+type TableStyle struct {
+	Heading   string // prefix of a section heading
+	Separator string // joins the dashes under the table header
+	Pipe      string // replaces | in the contents of a cell
+}
+
+var (
+	MarkdownStyle = TableStyle{Heading: "##", Separator: "|", Pipe: `\|`}
+	OrgStyle      = TableStyle{Heading: "*", Separator: "+", Pipe: `\vert{}`}
+)
+
+// UntrackedHeader names the columns of the untracked flakes table
+var UntrackedHeader = []string{
+	"Dashboard", "Status", "Job", "Test Name", "Sig",
+	"Flake Rate %", "Failure Rate %", "Runs", "Last Failure", "Url",
+}
+
+// TableReporter writes a summary table, a table of the untracked flakes,
+// tables of the effort on flake issues and a table of the report rows, as Markdown or Org-mode markup depending on its Style
+type TableReporter struct {
+	Style TableStyle
+}
+
+func (r *TableReporter) Report(w io.Writer, snap *snapshot.Snapshot) error {
+	summary := Summarise(snap)
+	var summaryRows [][]string
+	for _, sc := range summary.Statuses {
+		summaryRows = append(summaryRows,
+			[]string{string(sc.Status), fmt.Sprint(sc.Count), fmt.Sprintf("%2.1f", sc.Percent)})
+	}
+	summaryRows = append(summaryRows, []string{"Total", fmt.Sprint(summary.Total), "100.0"})
+
+	tracking := Track(snap)
+	var untrackedRows [][]string
+	for _, t := range tracking.Untracked {
+		var lastFailure string
+		if !t.Stats.LastFailure.IsZero() {
+			lastFailure = t.Stats.LastFailure.Format(time.UnixDate)
+		}
+		untrackedRows = append(untrackedRows, []string{
+			t.Dashboard, string(t.Status), t.Job, t.Test, t.Sig,
+			fmt.Sprintf("%2.1f", t.Stats.FlakeRate*100), fmt.Sprintf("%2.1f", t.Stats.FailureRate*100),
+			fmt.Sprint(t.Stats.Runs), lastFailure, t.Url,
+		})
+	}
+
+	effort := DistributeEffort(snap.Issues)
+	effortHeader := func(name string) []string {
+		header := []string{name}
+		for _, e := range rf.Efforts {
+			header = append(header, string(e))
+		}
+		return append(header, "Total")
+	}
+	effortRows := func(counts []EffortCount) [][]string {
+		var rows [][]string
+		for _, ec := range counts {
+			row := []string{ec.Name}
+			for _, e := range rf.Efforts {
+				row = append(row, fmt.Sprint(ec.Counts[e]))
+			}
+			rows = append(rows, append(row, fmt.Sprint(ec.Total)))
+		}
+		return rows
+	}
+
+	var rows [][]string
+	for _, row := range Rows(snap) {
+		rows = append(rows, row.Columns())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Summary %s\n\n", r.Style.Heading, snap.CollectedAt.Format(time.UnixDate))
+	r.writeTable(&b, []string{"Status", "Jobs", "%"}, summaryRows)
+	fmt.Fprintf(&b, "\n%s Untracked flakes\n\n", r.Style.Heading)
+	fmt.Fprintf(&b, "%d of %d flaking or failing tests have no open issue tracking them, most severe first\n\n",
+		len(tracking.Untracked), len(tracking.Untracked)+len(tracking.Tracked))
+	r.writeTable(&b, UntrackedHeader, untrackedRows)
+	fmt.Fprintf(&b, "\n%s Effort by job\n\n", r.Style.Heading)
+	r.writeTable(&b, effortHeader("Job"), effortRows(effort.ByJob))
+	fmt.Fprintf(&b, "\n%s Effort by SIG\n\n", r.Style.Heading)
+	r.writeTable(&b, effortHeader("SIG"), effortRows(effort.BySig))
+	fmt.Fprintf(&b, "\n%s Jobs\n\n", r.Style.Heading)
+	r.writeTable(&b, Header, rows)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTable writes a table with a header line to b
+func (r *TableReporter) writeTable(b *strings.Builder, header []string, rows [][]string) {
+	writeLine := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = r.escapeCell(cell)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(escaped, " | "))
+	}
+
+	writeLine(header)
+	dashes := make([]string, len(header))
+	for i := range dashes {
+		dashes[i] = "---"
+	}
+	fmt.Fprintf(b, "|%s|\n", strings.Join(dashes, r.Style.Separator))
+	for _, row := range rows {
+		writeLine(row)
+	}
+}
+
+// escapeCell stops the contents of a cell from breaking the table markup
+func (r *TableReporter) escapeCell(cell string) string {
+	cell = strings.Replace(cell, "\n", " ", -1)
+	return strings.Replace(cell, "|", r.Style.Pipe, -1)
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"sort"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+)
+
+// TrackedTest is a test of a flaking or failing job and the open issues
+// tracking it, a test with no open issues or linked bugs is untracked and
+// needs an issue
+type TrackedTest struct {
+	Dashboard string           `json:"dashboard"`
+	Status    ci.OverallStatus `json:"status"`
+	Job       string           `json:"job"`
+	Test      string           `json:"test"`
+	Sig       string           `json:"sig,omitempty"`
+	Stats     ci.TestStats     `json:"stats"`
+	Url       string           `json:"url"`
+	Tracked   bool             `json:"tracked"`
+	Issues    []rf.FlakeIssue  `json:"issues,omitempty"`
+}
+
+// Tracking classifies the tests of the flaking and failing jobs in a
+// snapshot by whether an open issue tracks them. Tests of jobs whose test
+// table could not be retrieved are not classified.
+type Tracking struct {
+	Tracked   []TrackedTest `json:"tracked"`
+	Untracked []TrackedTest `json:"untracked"` // most severe first
+}
+
+// Track classifies every test of the flaking and failing jobs in snap against
+// the issues collected with it
+func Track(snap *snapshot.Snapshot) Tracking {
+	var t Tracking
+	for _, cs := range snap.Collection.Dashboards {
+		for _, status := range []ci.OverallStatus{ci.FAILING, ci.FLAKY} {
+			jobs := cs.Jobs[status]
+			for _, jobName := range sortedJobNames(jobs) {
+				job := jobs[jobName]
+				if job.JobTestResults == nil {
+					continue
+				}
+				for _, test := range job.JobTestResults.Tests {
+					tt := TrackedTest{
+						Dashboard: cs.Name,
+						Status:    status,
+						Job:       jobName,
+						Test:      test.Name,
+						Sig:       test.Sig,
+						Stats:     test.Stats,
+						Url:       job.Url,
+						Issues:    openIssuesFor(snap.Issues, jobName, test.Name),
+					}
+					tt.Tracked = len(tt.Issues) > 0 || hasOpenIssue(test.LinkedBugs)
+					if tt.Tracked {
+						t.Tracked = append(t.Tracked, tt)
+					} else {
+						t.Untracked = append(t.Untracked, tt)
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(t.Untracked, func(i, j int) bool {
+		return ci.MoreSevere(t.Untracked[i].Stats, t.Untracked[j].Stats)
+	})
+	return t
+}
+
+// hasOpenIssue returns true if any of issues is open
+func hasOpenIssue(issues []ci.LinkedIssue) bool {
+	for _, i := range issues {
+		if i.IsOpen() {
+			return true
+		}
+	}
+	return false
+}
+
+// openIssuesFor returns the open issues that track test on job
+func openIssuesFor(issues []rf.FlakeIssue, job, test string) []rf.FlakeIssue {
+	var found []rf.FlakeIssue
+	for _, i := range issues {
+		if i.IsOpen() && i.Tracks(job, test) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
@@ -0,0 +1,100 @@
+package reportedflake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	SOURCE_BOARD       string = "board"
+	SOURCE_PROJECTS_V2 string = "projects-v2"
+	SOURCE_SEARCH      string = "search"
+)
+
+// BoardReader lists the issues on a project board, or found by a search
+type BoardReader interface {
+	BoardIssues(ctx context.Context, gh GitHub) ([]BoardIssue, error)
+}
+
+// BoardIssue is an issue on a project board and the column, or status, it is
+// in. Source names the board or search the issue was discovered by.
+type BoardIssue struct {
+	Owner  string
+	Repo   string
+	Number int
+	Column string
+	Source string
+	Issue  *github.Issue // set if the reader fetched the issue itself
+}
+
+// URL returns the GitHub page of the issue on bi
+func (bi BoardIssue) URL() string {
+	if bi.Issue != nil && bi.Issue.GetHTMLURL() != "" {
+		return bi.Issue.GetHTMLURL()
+	}
+	return fmt.Sprintf("https://github.com/%s/%s/issues/%d", bi.Owner, bi.Repo, bi.Number)
+}
+
+// ClassicBoard reads a classic project board over the REST API, Columns
+// overrides the names of the board's columns by ID
+type ClassicBoard struct {
+	ID      int64
+	Columns map[int64]string
+}
+
+func (b *ClassicBoard) BoardIssues(ctx context.Context, gh GitHub) ([]BoardIssue, error) {
+	cols, err := gh.ListProjectColumns(ctx, b.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Listing columns of project board %d %w", b.ID, err)
+	}
+
+	var issues []BoardIssue
+	for _, col := range cols {
+		column := col.GetName()
+		if name, ok := b.Columns[col.GetID()]; ok {
+			column = name
+		}
+		cards, err := gh.ListProjectCards(ctx, col.GetID())
+		if err != nil {
+			return nil, fmt.Errorf("Listing cards of column %s %w", column, err)
+		}
+		for _, card := range cards {
+			contentUrl := card.GetContentURL()
+			if contentUrl == "" { // a note rather than an issue
+				continue
+			}
+			owner, repo, number, err := parseIssueUrl(contentUrl)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, BoardIssue{
+				Owner: owner, Repo: repo, Number: number, Column: column, Source: b.Source(),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// Source names the board as the source of the issues on it
+func (b *ClassicBoard) Source() string {
+	return fmt.Sprintf("%s:%d", SOURCE_BOARD, b.ID)
+}
+
+// parseIssueUrl returns the owner, repo and number of the issue at an API
+// url, .../repos/<owner>/<repo>/issues/<number>, or an HTML url,
+// https://github.com/<owner>/<repo>/issues/<number>
+func parseIssueUrl(issueUrl string) (string, string, int, error) {
+	urlParts := strings.Split(strings.TrimSuffix(issueUrl, "/"), "/")
+	if len(urlParts) < 4 {
+		return "", "", 0, fmt.Errorf("Error spliting url %s", issueUrl)
+	}
+	number, err := strconv.Atoi(urlParts[len(urlParts)-1])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("Error parsing issue number of url %s %w", issueUrl, err)
+	}
+	return urlParts[len(urlParts)-4], urlParts[len(urlParts)-3], number, nil
+}
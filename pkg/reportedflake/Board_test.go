@@ -0,0 +1,112 @@
+package reportedflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testClient returns a GitHub client for the API served by handler
+func testClient(t *testing.T, handler http.Handler) (*Client, func()) {
+	srv := httptest.NewServer(handler)
+	client, err := NewClient(nil, srv.URL+"/")
+	if err != nil {
+		t.Fatalf("NewClient returned %v", err)
+	}
+	return client, srv.Close
+}
+
+// Tests that a classic board lists the issues on its cards, skipping notes,
+// naming columns as configured
+func TestClassicBoard(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects/1/columns", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 10, "name": "New (no response yet)"}, {"id": 11, "name": "Observing"}]`))
+	})
+	mux.HandleFunc("/projects/columns/10/cards", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"note": "a note"}, {"content_url": "https://api.github.com/repos/kubernetes/kubernetes/issues/1"}]`))
+	})
+	mux.HandleFunc("/projects/columns/11/cards", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"content_url": "https://api.github.com/repos/kubernetes/test-infra/issues/2"}]`))
+	})
+	client, done := testClient(t, mux)
+	defer done()
+
+	b := &ClassicBoard{ID: 1, Columns: map[int64]string{11: "observing"}}
+	issues, err := b.BoardIssues(context.Background(), client)
+	if err != nil {
+		t.Fatalf("BoardIssues returned %v", err)
+	}
+	expected := []BoardIssue{
+		{Owner: "kubernetes", Repo: "kubernetes", Number: 1, Column: "New (no response yet)", Source: "board:1"},
+		{Owner: "kubernetes", Repo: "test-infra", Number: 2, Column: "observing", Source: "board:1"},
+	}
+	if len(issues) != len(expected) || issues[0] != expected[0] || issues[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, issues)
+	}
+}
+
+// Tests that a projects-v2 board pages through its items, taking the column
+// of each issue from its status field and skipping other content
+func TestProjectV2Board(t *testing.T) {
+	pages := []string{
+		`{"data": {"owner": {"projectV2": {"items": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+			"nodes": [
+				{"status": {"name": "Triaged"}, "content": {"number": 1, "repository": {"name": "kubernetes", "owner": {"login": "kubernetes"}}}},
+				{"status": null, "content": {}}
+			]}}}}}`,
+		`{"data": {"owner": {"projectV2": {"items": {
+			"pageInfo": {"hasNextPage": false, "endCursor": "c2"},
+			"nodes": [
+				{"status": null, "content": {"number": 2, "repository": {"name": "test-infra", "owner": {"login": "kubernetes"}}}}
+			]}}}}}`,
+	}
+	var cursors []interface{}
+	client, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/graphql" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if req.Variables["login"] != "kubernetes" || req.Variables["field"] != DEFAULT_STATUS_FIELD {
+			t.Errorf("Unexpected variables %v", req.Variables)
+		}
+		cursors = append(cursors, req.Variables["cursor"])
+		w.Write([]byte(pages[len(cursors)-1]))
+	}))
+	defer done()
+
+	b := &ProjectV2Board{Owner: "kubernetes", Number: 68}
+	issues, err := b.BoardIssues(context.Background(), client)
+	if err != nil {
+		t.Fatalf("BoardIssues returned %v", err)
+	}
+	if len(cursors) != 2 || cursors[0] != nil || cursors[1] != "c1" {
+		t.Errorf("Expected to page through items, got cursors %v", cursors)
+	}
+	expected := []BoardIssue{
+		{Owner: "kubernetes", Repo: "kubernetes", Number: 1, Column: "Triaged", Source: "projects-v2:kubernetes/68"},
+		{Owner: "kubernetes", Repo: "test-infra", Number: 2, Source: "projects-v2:kubernetes/68"},
+	}
+	if len(issues) != len(expected) || issues[0] != expected[0] || issues[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, issues)
+	}
+}
+
+// Tests that GraphQL errors are returned
+func TestProjectV2BoardErrors(t *testing.T) {
+	client, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"owner": null}, "errors": [{"message": "Could not resolve to an Organization"}]}`))
+	}))
+	defer done()
+
+	b := &ProjectV2Board{Owner: "nobody", Number: 1}
+	if _, err := b.BoardIssues(context.Background(), client); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
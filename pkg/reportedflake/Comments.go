@@ -0,0 +1,73 @@
+package reportedflake
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
+)
+
+var ciLinkRE = regexp.MustCompile(`https://(prow\.k8s\.io|go\.k8s\.io/triage|storage\.googleapis\.com/k8s-gubernator/triage)[^\s)\]>"']*`)
+
+// Mention is a job, tests or prow and triage links found in the body or a
+// comment of an issue, URL is the comment's, or the issue's for its body
+type Mention struct {
+	URL       string
+	Dashboard string
+	Job       string
+	Tests     []string
+	Links     []string // prow and triage links
+}
+
+// parseMentions finds the jobs, tests and CI links in body, one Mention per
+// job linked to on TestGrid. Jobs are mentioned with the tests listed in body,
+// if any, or otherwise with issueTests. Prow links name prow jobs rather than
+// TestGrid tabs, so they are kept as Links only.
+func parseMentions(body, url string, issueTests []string) []Mention {
+	found := lint.FindTests(body)
+	tests := found
+	if len(tests) == 0 {
+		tests = issueTests
+	}
+	links := ciLinkRE.FindAllString(body, -1)
+
+	var mentions []Mention
+	seen := make(map[string]bool)
+	for _, tg := range lint.FindTestGridLinks(body) {
+		if tg.Job == "" || seen[tg.Job] {
+			continue
+		}
+		seen[tg.Job] = true
+		mentions = append(mentions, Mention{URL: url, Dashboard: tg.Dashboard, Job: tg.Job, Tests: tests})
+	}
+
+	if len(mentions) == 0 && (len(links) > 0 || len(found) > 0) {
+		// tests or links with no job are taken to be on the issue's job, a
+		// comment with links only is about the issue's tests
+		mentions = append(mentions, Mention{URL: url, Tests: tests})
+	}
+	if len(mentions) > 0 {
+		mentions[0].Links = links
+	}
+	return mentions
+}
+
+// addMentions records the jobs, tests and CI links mentioned in the body and
+// comments of i, fetching the comments of the issue
+func (rf *ReportedFlake) addMentions(gh GitHub, i *FlakeIssue, body string) error {
+	i.Mentions = parseMentions(body, i.URL, i.Tests)
+
+	ownerRepo := strings.Split(i.Repo, "/")
+	if len(ownerRepo) != 2 {
+		return nil
+	}
+	comments, err := gh.ListComments(context.Background(), ownerRepo[0], ownerRepo[1], i.Number)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		i.Mentions = append(i.Mentions, parseMentions(c.GetBody(), c.GetHTMLURL(), i.Tests)...)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package reportedflake
+
+import (
+	"net/http"
+	"testing"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	COMMENT_NEW_JOB = `Also flaking on https://testgrid.k8s.io/sig-release-master-informing#gce-cos-master-serial
+and https://prow.k8s.io/view/gcs/kubernetes-jenkins/logs/ci-kubernetes-e2e-gci-gce/1316088431282999296`
+	COMMENT_NEW_TEST = `Which test(s) are flaking:
+[sig-node] Pods should be restarted
+
+Triage: https://go.k8s.io/triage?test=Pods`
+	COMMENT_TRIAGE = `see https://go.k8s.io/triage?test=Deployment`
+)
+
+// Tests that comments mention jobs linked on TestGrid, with the issue's tests
+// unless they list their own, and keep prow and triage links as Links
+func TestParseMentions(t *testing.T) {
+	issueTests := []string{"[sig-apps] Deployment should roll"}
+
+	// the prow job is not a TestGrid tab, so it is not mentioned as a job
+	mentions := parseMentions(COMMENT_NEW_JOB, "comment-1", issueTests)
+	if len(mentions) != 1 {
+		t.Fatalf("Expected a mention of the TestGrid job only, got %+v", mentions)
+	}
+	if m := mentions[0]; m.URL != "comment-1" || m.Dashboard != "sig-release-master-informing" ||
+		m.Job != "gce-cos-master-serial" || len(m.Tests) != 1 || len(m.Links) != 1 {
+		t.Errorf("Unexpected TestGrid mention %+v", m)
+	}
+
+	mentions = parseMentions(COMMENT_NEW_TEST, "comment-2", issueTests)
+	if len(mentions) != 1 || mentions[0].Job != "" || len(mentions[0].Tests) != 1 ||
+		mentions[0].Tests[0] != "[sig-node] Pods should be restarted" || len(mentions[0].Links) != 1 {
+		t.Errorf("Expected mention of a test on the issue's job, got %+v", mentions)
+	}
+
+	if mentions = parseMentions("+1", "comment-3", issueTests); len(mentions) != 0 {
+		t.Errorf("Expected no mentions, got %+v", mentions)
+	}
+
+	// a comment with only a triage link does not widen the issue to every
+	// test on its job
+	mentions = parseMentions(COMMENT_TRIAGE, "comment-4", issueTests)
+	if len(mentions) != 1 || len(mentions[0].Links) != 1 {
+		t.Fatalf("Expected a mention of the triage link, got %+v", mentions)
+	}
+	i := FlakeIssue{Job: "job-a", Tests: issueTests, Mentions: mentions}
+	if !i.Tracks("job-a", issueTests[0]) || i.Tracks("job-a", "[sig-node] Pods should be restarted") {
+		t.Errorf("Expected the triage link to track only the issue's test, got %+v", mentions[0])
+	}
+}
+
+// Tests that an issue tracks the jobs and tests mentioned in its comments
+func TestTracksMentions(t *testing.T) {
+	i := FlakeIssue{
+		Job:   "job-a",
+		Tests: []string{"test-1"},
+		Mentions: []Mention{
+			{Job: "job-b", Tests: []string{"test-1"}},
+			{Tests: []string{"test-2"}},
+		},
+	}
+	for _, s := range []struct {
+		job, test string
+		tracked   bool
+	}{
+		{"job-a", "[sig-x] test-1", true},
+		{"job-b", "test-1", true},
+		{"job-a", "test-2", true},
+		{"job-b", "test-2", false},
+		{"job-c", "test-1", false},
+	} {
+		if i.Tracks(s.job, s.test) != s.tracked {
+			t.Errorf("Expected Tracks(%s, %s) to be %v", s.job, s.test, s.tracked)
+		}
+	}
+}
+
+// Tests that the mentions in every comment of an issue are collected
+func TestAddMentions(t *testing.T) {
+	client, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/kubernetes/kubernetes/issues/1/comments" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"html_url": "https://github.com/kubernetes/kubernetes/issues/1#issuecomment-1", "body": "+1"},
+			{"html_url": "https://github.com/kubernetes/kubernetes/issues/1#issuecomment-2", "body": "https://testgrid.k8s.io/d#job-b"}
+		]`))
+	}))
+	defer done()
+
+	rf := &ReportedFlake{Logger: log.New()}
+	i := &FlakeIssue{
+		LinkedIssue: ci.LinkedIssue{Repo: "kubernetes/kubernetes", Number: 1, URL: "https://github.com/kubernetes/kubernetes/issues/1"},
+		Job:         "job-a",
+	}
+	if err := rf.addMentions(client, i, "Testgrid link:\nhttps://testgrid.k8s.io/d#job-a"); err != nil {
+		t.Fatalf("addMentions returned %v", err)
+	}
+	if len(i.Mentions) != 2 || i.Mentions[0].URL != i.URL ||
+		i.Mentions[1].Job != "job-b" || i.Mentions[1].URL != "https://github.com/kubernetes/kubernetes/issues/1#issuecomment-2" {
+		t.Errorf("Unexpected mentions %+v", i.Mentions)
+	}
+}
@@ -0,0 +1,112 @@
+package reportedflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Effort categorises the work done on a flake issue
+type Effort string
+
+const (
+	EFFORT_AWAITING_RESPONSE Effort = "awaiting response"
+	EFFORT_TRIAGED           Effort = "triaged"
+	EFFORT_PR_SUBMITTED      Effort = "PR submitted"
+	EFFORT_MONITORING        Effort = "monitoring"
+	EFFORT_FIXED             Effort = "fixed"
+)
+
+// Efforts lists every Effort from the least to the most progressed
+var Efforts = []Effort{
+	EFFORT_AWAITING_RESPONSE, EFFORT_TRIAGED, EFFORT_PR_SUBMITTED, EFFORT_MONITORING, EFFORT_FIXED,
+}
+
+// columnEfforts maps the start of a lower cased project board column name to
+// the effort cards in the column have had, e.g. the CI Signal board's
+// "Under investigation (prioritized)" column holds triaged issues
+var columnEfforts = []struct {
+	prefix string
+	effort Effort
+}{
+	{"new", EFFORT_AWAITING_RESPONSE},
+	{"under investigation", EFFORT_TRIAGED},
+	{"triage", EFFORT_TRIAGED},
+	{"observ", EFFORT_MONITORING},
+	{"monitor", EFFORT_MONITORING},
+	{"resolved", EFFORT_FIXED},
+	{"fixed", EFFORT_FIXED},
+	{"done", EFFORT_FIXED},
+}
+
+// PullRequest is a pull request that references a flake issue
+type PullRequest struct {
+	Number int
+	URL    string
+	State  string
+	Merged bool
+}
+
+// Submitted returns true if p is open or was merged, rather than closed
+// without merging
+func (p PullRequest) Submitted() bool {
+	return p.State == ISSUE_OPEN || p.Merged
+}
+
+// Effort categorises the work done on i from its state, the board column its
+// card is in and the pull requests that reference it
+func (i FlakeIssue) Effort() Effort {
+	if i.State == ISSUE_CLOSED {
+		return EFFORT_FIXED
+	}
+	column := EFFORT_AWAITING_RESPONSE
+	name := strings.ToLower(strings.TrimSpace(i.Column))
+	for _, ce := range columnEfforts {
+		if strings.HasPrefix(name, ce.prefix) {
+			column = ce.effort
+			break
+		}
+	}
+	if column == EFFORT_FIXED || column == EFFORT_MONITORING {
+		return column
+	}
+	for _, pr := range i.PullRequests {
+		if pr.Submitted() {
+			return EFFORT_PR_SUBMITTED
+		}
+	}
+	return column
+}
+
+// getLinkedPullRequests returns the pull requests that cross-reference the
+// issue number in repo (owner/name)
+func (rf *ReportedFlake) getLinkedPullRequests(gh GitHub, repo string, number int) ([]PullRequest, error) {
+	ownerName := strings.Split(repo, "/")
+	if len(ownerName) != 2 {
+		return nil, fmt.Errorf("Error splitting repo %s", repo)
+	}
+	events, err := gh.ListTimeline(context.Background(), ownerName[0], ownerName[1], number)
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []PullRequest
+	seen := make(map[string]bool)
+	for _, e := range events {
+		if e.Event != CROSS_REFERENCED_EVENT || e.Source == nil || e.Source.Issue == nil || e.Source.Issue.PullRequest == nil {
+			continue
+		}
+		pr := e.Source.Issue
+		if seen[pr.HTMLURL] {
+			continue
+		}
+		seen[pr.HTMLURL] = true
+		prs = append(prs, PullRequest{
+			Number: pr.Number,
+			URL:    pr.HTMLURL,
+			State:  pr.State,
+			Merged: pr.PullRequest.MergedAt != nil,
+		})
+	}
+	return prs, nil
+}
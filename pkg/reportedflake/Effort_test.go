@@ -0,0 +1,62 @@
+package reportedflake
+
+import (
+	"net/http"
+	"testing"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tests that the effort on an issue follows its state, board column and
+// linked pull requests
+func TestEffort(t *testing.T) {
+	pr := []PullRequest{{Number: 1, State: ISSUE_OPEN}}
+	merged := []PullRequest{{Number: 1, State: ISSUE_CLOSED, Merged: true}}
+	abandoned := []PullRequest{{Number: 1, State: ISSUE_CLOSED}}
+	issue := func(column, state string, prs []PullRequest) FlakeIssue {
+		return FlakeIssue{LinkedIssue: ci.LinkedIssue{Column: column, State: state}, PullRequests: prs}
+	}
+	scenarios := []struct {
+		issue  FlakeIssue
+		effort Effort
+	}{
+		{issue("", ISSUE_OPEN, nil), EFFORT_AWAITING_RESPONSE},
+		{issue("New (no response yet)", "", nil), EFFORT_AWAITING_RESPONSE},
+		{issue("Under investigation (prioritized)", "", nil), EFFORT_TRIAGED},
+		{issue("Under investigation (prioritized)", "", pr), EFFORT_PR_SUBMITTED},
+		{issue("Under investigation (prioritized)", "", merged), EFFORT_PR_SUBMITTED},
+		{issue("Under investigation (prioritized)", "", abandoned), EFFORT_TRIAGED},
+		{issue("observing", "", pr), EFFORT_MONITORING},
+		{issue("Resolved (week ending 10/16)", "", nil), EFFORT_FIXED},
+		{issue("New", ISSUE_CLOSED, nil), EFFORT_FIXED},
+	}
+	for _, s := range scenarios {
+		if got := s.issue.Effort(); got != s.effort {
+			t.Errorf("Expected %+v to be %s, got %s", s.issue, s.effort, got)
+		}
+	}
+}
+
+// Tests that only pull requests cross-referencing an issue are linked to it
+func TestGetLinkedPullRequests(t *testing.T) {
+	client, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/kubernetes/kubernetes/issues/1/timeline" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"event": "labeled"},
+			{"event": "cross-referenced", "source": {"issue": {"number": 2, "html_url": "https://github.com/kubernetes/kubernetes/issues/2", "state": "open"}}},
+			{"event": "cross-referenced", "source": {"issue": {"number": 3, "html_url": "https://github.com/kubernetes/kubernetes/pull/3", "state": "closed", "pull_request": {"merged_at": "2020-10-01T09:00:00Z"}}}},
+			{"event": "cross-referenced", "source": {"issue": {"number": 3, "html_url": "https://github.com/kubernetes/kubernetes/pull/3", "state": "closed", "pull_request": {"merged_at": "2020-10-01T09:00:00Z"}}}}
+		]`))
+	}))
+	defer done()
+
+	rf := &ReportedFlake{Logger: log.New()}
+	prs, err := rf.getLinkedPullRequests(client, "kubernetes/kubernetes", 1)
+	if err != nil || len(prs) != 1 || prs[0].Number != 3 || prs[0].State != "closed" || !prs[0].Merged {
+		t.Errorf("Expected pull request 3, got %+v %v", prs, err)
+	}
+}
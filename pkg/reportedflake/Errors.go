@@ -0,0 +1,42 @@
+package reportedflake
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stages of collecting an issue on a card, reported in a CardError
+const (
+	STAGE_GET_ISSUE     string = "get-issue"
+	STAGE_COMMENTS      string = "comments"
+	STAGE_PULL_REQUESTS string = "pull-requests"
+)
+
+// CardError is the failure to collect the issue at URL, on a card of a
+// project board or found by a search, at Stage
+type CardError struct {
+	URL   string
+	Stage string
+	Err   error
+}
+
+func (e CardError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.URL, e.Stage, e.Err)
+}
+
+func (e CardError) Unwrap() error {
+	return e.Err
+}
+
+// CardErrors lists the cards that could not be fully collected. Issues that
+// failed at STAGE_COMMENTS or STAGE_PULL_REQUESTS are still collected,
+// without their mentions or pull requests.
+type CardErrors []CardError
+
+func (e CardErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("Could not collect %d card(s)\n  %s", len(e), strings.Join(msgs, "\n  "))
+}
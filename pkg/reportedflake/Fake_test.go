@@ -0,0 +1,27 @@
+package reportedflake
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const FIXTURES_DIR string = "testdata/github"
+
+// fakeGitHub returns a client for a fake GitHub API serving the fixture at
+// FIXTURES_DIR/<path>.json for each request, GraphQL queries are answered
+// from graphql.json. Paths without a fixture are not found.
+func fakeGitHub(t *testing.T) (*Client, func()) {
+	return testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(r.URL.Path, "/")
+		if r.Method != "GET" && path != GRAPHQL_PATH {
+			http.Error(w, `{"message": "Method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		fixture := filepath.Join(FIXTURES_DIR, filepath.FromSlash(path)+".json")
+		t.Logf("%s %s served from %s", r.Method, r.URL, fixture)
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, fixture)
+	}))
+}
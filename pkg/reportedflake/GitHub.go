@@ -0,0 +1,200 @@
+package reportedflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	GITHUB_TOKEN_ENV        string = "GITHUB_AUTH_TOKEN"
+	GITHUB_PER_PAGE         int    = 100
+	GRAPHQL_PATH            string = "graphql" // relative to the client's BaseURL
+	TIMELINE_PREVIEW_HEADER string = "application/vnd.github.mockingbird-preview"
+	CROSS_REFERENCED_EVENT  string = "cross-referenced"
+)
+
+// GitHub is the part of the GitHub API the tracker reads flake issues with,
+// each List and Search method returns every page of results
+type GitHub interface {
+	ListProjectColumns(ctx context.Context, projectID int64) ([]*github.ProjectColumn, error)
+	ListProjectCards(ctx context.Context, columnID int64) ([]*github.ProjectCard, error)
+	GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error)
+	ListComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error)
+	ListTimeline(ctx context.Context, owner, repo string, number int) ([]TimelineEvent, error)
+	SearchIssues(ctx context.Context, query string) ([]github.Issue, error)
+	GraphQL(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error
+}
+
+// TimelineEvent is the part of an issue timeline event that links to pull
+// requests, go-github's Timeline does not decode the source issue
+type TimelineEvent struct {
+	Event  string `json:"event"`
+	Source *struct {
+		Issue *TimelineIssue `json:"issue"`
+	} `json:"source"`
+}
+
+// TimelineIssue is the issue or pull request a TimelineEvent comes from,
+// PullRequest is nil for issues
+type TimelineIssue struct {
+	Number      int    `json:"number"`
+	HTMLURL     string `json:"html_url"`
+	State       string `json:"state"`
+	PullRequest *struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// Client implements GitHub with go-github
+type Client struct {
+	*github.Client
+}
+
+// NewClient returns a Client making requests with httpClient, which handles
+// authentication, to the API at baseUrl, or to GitHub if it is empty
+func NewClient(httpClient *http.Client, baseUrl string) (*Client, error) {
+	c := github.NewClient(httpClient)
+	if baseUrl != "" {
+		u, err := c.BaseURL.Parse(baseUrl)
+		if err != nil {
+			return nil, err
+		}
+		c.BaseURL = u
+	}
+	return &Client{c}, nil
+}
+
+// NewClientFromEnv returns a Client authenticated with the token in
+// GITHUB_TOKEN_ENV making requests with transport, e.g. a RateLimiter, or
+// http.DefaultTransport when nil
+func NewClientFromEnv(ctx context.Context, transport http.RoundTripper) (*Client, error) {
+	token := os.Getenv(GITHUB_TOKEN_ENV)
+	if token == "" {
+		return nil, errors.New(GITHUB_TOKEN_ENV + " is not set in process env.")
+	}
+	if transport != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	return NewClient(oauth2.NewClient(ctx, ts), "")
+}
+
+// ListProjectColumns returns the columns of the classic project projectID
+func (c *Client) ListProjectColumns(ctx context.Context, projectID int64) ([]*github.ProjectColumn, error) {
+	var all []*github.ProjectColumn
+	opt := &github.ListOptions{PerPage: GITHUB_PER_PAGE}
+	for {
+		cols, resp, err := c.Projects.ListProjectColumns(ctx, projectID, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, cols...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// ListProjectCards returns the cards in the classic project column columnID
+func (c *Client) ListProjectCards(ctx context.Context, columnID int64) ([]*github.ProjectCard, error) {
+	var all []*github.ProjectCard
+	opt := &github.ProjectCardListOptions{ListOptions: github.ListOptions{PerPage: GITHUB_PER_PAGE}}
+	for {
+		cards, resp, err := c.Projects.ListProjectCards(ctx, columnID, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, cards...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// GetIssue returns issue number of owner/repo
+func (c *Client) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	issue, _, err := c.Issues.Get(ctx, owner, repo, number)
+	return issue, err
+}
+
+// ListComments returns the comments on issue number of owner/repo
+func (c *Client) ListComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error) {
+	var all []*github.IssueComment
+	opt := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: GITHUB_PER_PAGE}}
+	for {
+		comments, resp, err := c.Issues.ListComments(ctx, owner, repo, number, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, comments...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// ListTimeline returns the timeline events of issue number of owner/repo,
+// using the raw API as go-github does not decode the events' source
+func (c *Client) ListTimeline(ctx context.Context, owner, repo string, number int) ([]TimelineEvent, error) {
+	var all []TimelineEvent
+	page := 1
+	for {
+		u := fmt.Sprintf("repos/%s/%s/issues/%d/timeline?per_page=%d&page=%d", owner, repo, number, GITHUB_PER_PAGE, page)
+		req, err := c.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", TIMELINE_PREVIEW_HEADER)
+		var events []TimelineEvent
+		resp, err := c.Do(ctx, req, &events)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, events...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+// SearchIssues returns the issues and pull requests matching query
+func (c *Client) SearchIssues(ctx context.Context, query string) ([]github.Issue, error) {
+	var all []github.Issue
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: GITHUB_PER_PAGE}}
+	for {
+		result, resp, err := c.Search.Issues(ctx, query, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Issues...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// GraphQL runs query with variables decoding the response into v, which
+// should have an Errors field to receive GraphQL errors
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	req, err := c.NewRequest("POST", GRAPHQL_PATH, map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.Do(ctx, req, v)
+	return err
+}
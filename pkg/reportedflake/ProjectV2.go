@@ -0,0 +1,133 @@
+package reportedflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	DEFAULT_STATUS_FIELD string = "Status"
+)
+
+// projectV2ItemsQuery pages through the issues on a Projects (v2) board
+// owned by an organization or user, %s is replaced by the owner type
+const projectV2ItemsQuery = `query($login: String!, $number: Int!, $field: String!, $cursor: String) {
+  owner: %s(login: $login) {
+    projectV2(number: $number) {
+      items(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          status: fieldValueByName(name: $field) {
+            ... on ProjectV2ItemFieldSingleSelectValue { name }
+          }
+          content {
+            ... on Issue { number repository { name owner { login } } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// ProjectV2Board reads a Projects (v2) board over the GraphQL API, the
+// column of an issue is the value of its single select StatusField
+type ProjectV2Board struct {
+	Owner       string // login of the organization, or user, owning the board
+	User        bool   // true if Owner is a user
+	Number      int    // number of the project in the url of the board
+	StatusField string // DEFAULT_STATUS_FIELD when empty
+}
+
+// projectV2Items is the response to projectV2ItemsQuery
+type projectV2Items struct {
+	Data struct {
+		Owner *struct {
+			ProjectV2 *struct {
+				Items struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						Status *struct {
+							Name string `json:"name"`
+						} `json:"status"`
+						Content *struct {
+							Number     int `json:"number"`
+							Repository struct {
+								Name  string `json:"name"`
+								Owner struct {
+									Login string `json:"login"`
+								} `json:"owner"`
+							} `json:"repository"`
+						} `json:"content"`
+					} `json:"nodes"`
+				} `json:"items"`
+			} `json:"projectV2"`
+		} `json:"owner"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (b *ProjectV2Board) BoardIssues(ctx context.Context, gh GitHub) ([]BoardIssue, error) {
+	ownerType := "organization"
+	if b.User {
+		ownerType = "user"
+	}
+	field := b.StatusField
+	if field == "" {
+		field = DEFAULT_STATUS_FIELD
+	}
+
+	var issues []BoardIssue
+	var cursor *string
+	for {
+		var resp projectV2Items
+		err := gh.GraphQL(ctx, fmt.Sprintf(projectV2ItemsQuery, ownerType), map[string]interface{}{
+			"login": b.Owner, "number": b.Number, "field": field, "cursor": cursor,
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("Querying project %s/%d %w", b.Owner, b.Number, err)
+		}
+		if len(resp.Errors) > 0 {
+			msgs := make([]string, len(resp.Errors))
+			for i, e := range resp.Errors {
+				msgs[i] = e.Message
+			}
+			return nil, fmt.Errorf("Querying project %s/%d %s", b.Owner, b.Number, strings.Join(msgs, ", "))
+		}
+		if resp.Data.Owner == nil || resp.Data.Owner.ProjectV2 == nil {
+			return nil, fmt.Errorf("Project %s/%d not found", b.Owner, b.Number)
+		}
+
+		items := resp.Data.Owner.ProjectV2.Items
+		for _, node := range items.Nodes {
+			if node.Content == nil || node.Content.Number == 0 { // a draft issue or pull request
+				continue
+			}
+			issue := BoardIssue{
+				Owner:  node.Content.Repository.Owner.Login,
+				Repo:   node.Content.Repository.Name,
+				Number: node.Content.Number,
+				Source: b.Source(),
+			}
+			if node.Status != nil {
+				issue.Column = node.Status.Name
+			}
+			issues = append(issues, issue)
+		}
+		if !items.PageInfo.HasNextPage {
+			return issues, nil
+		}
+		endCursor := items.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+// Source names the board as the source of the issues on it
+func (b *ProjectV2Board) Source() string {
+	return fmt.Sprintf("%s:%s/%d", SOURCE_PROJECTS_V2, b.Owner, b.Number)
+}
@@ -0,0 +1,209 @@
+package reportedflake
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RobertKielty/flake-tracker/pkg/httpcache"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	HEADER_RATE_LIMIT     string = "X-RateLimit-Limit"
+	HEADER_RATE_REMAINING string = "X-RateLimit-Remaining"
+	HEADER_RATE_RESET     string = "X-RateLimit-Reset"
+	HEADER_RATE_RESOURCE  string = "X-RateLimit-Resource"
+
+	RESOURCE_CORE    string = "core"
+	RESOURCE_SEARCH  string = "search"
+	RESOURCE_GRAPHQL string = "graphql"
+)
+
+// QuotaUsage is the GitHub API quota of a rate limit resource, e.g. core or
+// search, used by the requests made through a RateLimiter. Requests answered
+// with 304 Not Modified do not count against the quota.
+type QuotaUsage struct {
+	Resource    string
+	Requests    int
+	NotModified int
+	Limit       int
+	Remaining   int
+	Reset       time.Time
+}
+
+// Consumed returns the number of requests that counted against the quota
+func (u QuotaUsage) Consumed() int {
+	return u.Requests - u.NotModified
+}
+
+// RateLimiter is an http.RoundTripper for the GitHub API that tracks the
+// quota left from the rate limit headers of each response, waiting for the
+// quota to reset before making a request when it is exhausted. If Cache is
+// set GETs are made conditional on the ETag of the cached response, which is
+// returned when GitHub answers that it was not modified.
+type RateLimiter struct {
+	Transport http.RoundTripper // http.DefaultTransport when nil
+	Cache     *httpcache.Store
+	Logger    *log.Logger
+
+	mu    sync.Mutex
+	usage map[string]*QuotaUsage
+
+	// now and sleep are replaced by tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// RoundTrip makes req once the quota of its resource allows
+func (l *RateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := resourceOf(req)
+	l.wait(resource)
+
+	var cached *httpcache.Entry
+	if l.Cache != nil && req.Method == "GET" {
+		var err error
+		if cached, err = l.Cache.Get(req.URL.String()); err != nil {
+			l.logger().Warnf("Ignoring the cached response to %s %v", req.URL, err)
+			cached = nil
+		}
+		if cached != nil && cached.Header.Get("ETag") != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", cached.Header.Get("ETag"))
+		}
+	}
+
+	resp, err := l.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	l.track(resource, resp)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		return cached.Response(req), nil
+	case resp.StatusCode == http.StatusOK && l.Cache != nil && req.Method == "GET" && resp.Header.Get("ETag") != "":
+		return l.store(req, resp)
+	}
+	return resp, nil
+}
+
+// Usage returns the quota used by each resource requested, by resource name
+func (l *RateLimiter) Usage() []QuotaUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var usage []QuotaUsage
+	for _, u := range l.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Resource < usage[j].Resource })
+	return usage
+}
+
+// wait sleeps until the quota of resource resets if it is exhausted
+func (l *RateLimiter) wait(resource string) {
+	l.mu.Lock()
+	var wait time.Duration
+	if u, ok := l.usage[resource]; ok && u.Limit > 0 && u.Remaining == 0 {
+		wait = u.Reset.Sub(l.clock()) + time.Second // allow for clock skew
+	}
+	l.mu.Unlock()
+	if wait <= 0 {
+		return
+	}
+	l.logger().Warnf("GitHub %s quota exhausted, waiting %s for it to reset", resource, wait.Round(time.Second))
+	if l.sleep != nil {
+		l.sleep(wait)
+	} else {
+		time.Sleep(wait)
+	}
+}
+
+// track counts the request that got resp against resource, updating the quota
+// left from its headers
+func (l *RateLimiter) track(resource string, resp *http.Response) {
+	if r := resp.Header.Get(HEADER_RATE_RESOURCE); r != "" {
+		resource = r
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.usage == nil {
+		l.usage = make(map[string]*QuotaUsage)
+	}
+	u, ok := l.usage[resource]
+	if !ok {
+		u = &QuotaUsage{Resource: resource}
+		l.usage[resource] = u
+	}
+	u.Requests++
+	if resp.StatusCode == http.StatusNotModified {
+		u.NotModified++
+	}
+	if limit, err := strconv.Atoi(resp.Header.Get(HEADER_RATE_LIMIT)); err == nil {
+		u.Limit = limit
+	}
+	if remaining, err := strconv.Atoi(resp.Header.Get(HEADER_RATE_REMAINING)); err == nil {
+		u.Remaining = remaining
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get(HEADER_RATE_RESET), 10, 64); err == nil {
+		u.Reset = time.Unix(reset, 0)
+	}
+}
+
+// store caches the body of resp, returning a response that reads it again
+func (l *RateLimiter) store(req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := &httpcache.Entry{
+		URL:      req.URL.String(),
+		StoredAt: l.clock(),
+		Status:   resp.StatusCode,
+		Header:   resp.Header,
+		Body:     body,
+	}
+	if err = l.Cache.Put(e); err != nil {
+		l.logger().Warnf("Could not cache the response to %s %v", req.URL, err)
+	}
+	return e.Response(req), nil
+}
+
+func (l *RateLimiter) transport() http.RoundTripper {
+	if l.Transport == nil {
+		return http.DefaultTransport
+	}
+	return l.Transport
+}
+
+func (l *RateLimiter) logger() *log.Logger {
+	if l.Logger == nil {
+		return log.StandardLogger()
+	}
+	return l.Logger
+}
+
+func (l *RateLimiter) clock() time.Time {
+	if l.now == nil {
+		return time.Now()
+	}
+	return l.now()
+}
+
+// resourceOf returns the rate limit resource req counts against
+func resourceOf(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	switch {
+	case strings.HasPrefix(path, "search/"):
+		return RESOURCE_SEARCH
+	case path == GRAPHQL_PATH:
+		return RESOURCE_GRAPHQL
+	}
+	return RESOURCE_CORE
+}
@@ -0,0 +1,85 @@
+package reportedflake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RobertKielty/flake-tracker/pkg/httpcache"
+)
+
+// Tests that an issue that has not changed is served from the cache once
+// GitHub answers the conditional request with 304 Not Modified
+func TestRateLimiterConditionalRequests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	limiter := &RateLimiter{Cache: &httpcache.Store{Dir: dir}}
+	srv, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HEADER_RATE_LIMIT, "5000")
+		w.Header().Set(HEADER_RATE_REMAINING, "4999")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"number": 1, "title": "a flake"}`))
+	}))
+	defer done()
+	client, err := NewClient(&http.Client{Transport: limiter}, srv.BaseURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 2; run++ {
+		issue, err := client.GetIssue(context.Background(), "kubernetes", "kubernetes", 1)
+		if err != nil || issue.GetTitle() != "a flake" {
+			t.Fatalf("Run %d: expected the issue, got %+v %v", run, issue, err)
+		}
+	}
+	usage := limiter.Usage()
+	if len(usage) != 1 || usage[0].Resource != RESOURCE_CORE || usage[0].Requests != 2 ||
+		usage[0].Consumed() != 1 || usage[0].Remaining != 4999 {
+		t.Errorf("Expected one request to use quota, got %+v", usage)
+	}
+}
+
+// Tests that requests wait for the quota of their resource to reset once it
+// is exhausted
+func TestRateLimiterWaitsForReset(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	var waited time.Duration
+	limiter := &RateLimiter{
+		now:   func() time.Time { return now },
+		sleep: func(d time.Duration) { waited += d },
+	}
+	srv, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HEADER_RATE_LIMIT, "30")
+		w.Header().Set(HEADER_RATE_REMAINING, "0")
+		w.Header().Set(HEADER_RATE_RESET, strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+		w.Write([]byte(`{"total_count": 0, "items": []}`))
+	}))
+	defer done()
+	client, err := NewClient(&http.Client{Transport: limiter}, srv.BaseURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.SearchIssues(context.Background(), "label:kind/flake"); err != nil || waited != 0 {
+		t.Fatalf("Expected the first search not to wait, waited %s %v", waited, err)
+	}
+	client.GetIssue(context.Background(), "kubernetes", "kubernetes", 1)
+	if waited != 0 {
+		t.Errorf("Expected the core quota not to be exhausted, waited %s", waited)
+	}
+	if _, err = client.SearchIssues(context.Background(), "label:kind/flake"); err != nil || waited < time.Minute {
+		t.Errorf("Expected the search to wait for the reset, waited %s %v", waited, err)
+	}
+}
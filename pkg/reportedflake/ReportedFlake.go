@@ -1,35 +1,27 @@
 package reportedflake
 
 import (
-	"bufio"
 	"context"
 	"errors"
-	"fmt"
-	"os"
-	"regexp"
 	"strconv"
 	"strings"
 
 	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	"github.com/RobertKielty/flake-tracker/pkg/lint"
 	"github.com/google/go-github/github"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/oauth2"
 )
 
 const (
-	FLAKE_TEMPLATE_HEADER_WTAF      string = "Which test(s) are flaking:"
-	FLAKE_TEMPLATE_HEADER_TGL       string = "Testgrid link:"
-	ciSignalBoardId                 int64  = 2093513
-	ciSignalNewCardColId            int64  = 4212817
-	ciSignalUnderInvestigationColId int64  = 4212819
-	ciSignalObservingColId          int64  = 4212821
-	TG_MISSING                      string = "missing"
-)
-
-var (
-	tgUrl    = regexp.MustCompile(`https://testgrid.k8s.io/.+`)
-	tgDashRE = regexp.MustCompile(`o/(.+)#`)
-	tgJobRE  = regexp.MustCompile(`#(.+)`)
+	FLAKE_TEMPLATE_HEADER_WTAF           string = lint.HEADER_TESTS
+	FLAKE_TEMPLATE_HEADER_TGL            string = lint.HEADER_TESTGRID
+	CI_SIGNAL_BOARD_ID                   int64  = 2093513
+	CI_SIGNAL_NEW_CARD_COL_ID            int64  = 4212817
+	CI_SIGNAL_UNDER_INVESTIGATION_COL_ID int64  = 4212819
+	CI_SIGNAL_OBSERVING_COL_ID           int64  = 4212821
+	TG_MISSING                           string = "missing"
+	ISSUE_OPEN                           string = ci.ISSUE_OPEN
+	ISSUE_CLOSED                         string = ci.ISSUE_CLOSED
 )
 
 // ReportedFlake - issue logged on Github for a test that produces non-deterministic results
@@ -37,223 +29,244 @@ type ReportedFlake struct {
 	ghId, repo, job string
 	tests           []string
 	Logger          *log.Logger
-	CiStatus        *ci.CiStatus
+	Collection      *ci.Collection
+	Board           BoardReader  // Project board or search to collect from, the CI_SIGNAL_BOARD_ID ClassicBoard when nil
+	GitHub          GitHub       // API issues are read from, a rate limited Client authenticated from GITHUB_TOKEN_ENV when nil
+	Issues          []FlakeIssue // Issues decorated by CollectIssuesFromBoard
 }
 
-// Decoration of a GH Issue with flake-related data extracted from issue comments
-type actualFlake struct {
-	ghIssue   *github.Issue
-	dashboard string
-	job       string
-	tests     []string
+// FlakeIssue is a GH Issue decorated with flake-related data extracted from it
+type FlakeIssue struct {
+	ci.LinkedIssue
+	Mentions     []Mention // Jobs and tests mentioned in the body and comments
+	PullRequests []PullRequest
+	Dashboard    string
+	Job          string
+	Tests        []string
 }
 
-// parseTests collects tests referenced in the body of a formatted Flake Issue on GitHub
-// Each non-empty line between "Which test(s) are flaking:" and Testgrid link:
-// is congetTestssidered to be a test
-func ParseTests(b string) ([]string, error) {
-	var tests []string
-
-	start := strings.Index(b, FLAKE_TEMPLATE_HEADER_WTAF) + len(FLAKE_TEMPLATE_HEADER_WTAF)
-	end := strings.Index(b, FLAKE_TEMPLATE_HEADER_TGL)
-
-	if start == -1 {
-		return nil, errors.New("Missing header " + FLAKE_TEMPLATE_HEADER_WTAF)
+// Tracks returns true if i reports test flaking on job, in its body or in
+// any of its comments. An issue, or a mention, that names no tests tracks
+// every test on its job. Tests named in the issue match a test whose name
+// contains them, as issues often leave out the [sig-...] prefix
+func (i FlakeIssue) Tracks(job, test string) bool {
+	if tracks(i.Job, i.Tests, job, test) {
+		return true
 	}
-
-	if end == -1 {
-		return nil, errors.New("Missing header " + FLAKE_TEMPLATE_HEADER_TGL)
+	for _, m := range i.Mentions {
+		mentionedJob := m.Job
+		if mentionedJob == "" {
+			mentionedJob = i.Job
+		}
+		if tracks(mentionedJob, m.Tests, job, test) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Start reading after "Which test(s) are flaking:"
-	r := strings.NewReader(b[start:end])
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		t := s.Text()
-		if len(t) > 1 { // TODO this guards against empty line matches nicer way anyone?
-			tests = append(tests, t)
+// tracks returns true if test on job is one of tests on trackedJob
+func tracks(trackedJob string, tests []string, job, test string) bool {
+	if trackedJob != job {
+		return false
+	}
+	if len(tests) == 0 {
+		return true
+	}
+	for _, t := range tests {
+		if t == test || strings.Contains(test, t) {
+			return true
 		}
 	}
-	return tests, nil
+	return false
+}
+
+// ParseTests collects tests referenced in the body of a formatted Flake Issue on GitHub
+// Each non-empty line between "Which test(s) are flaking:" and Testgrid link:
+// is considered to be a test
+func ParseTests(b string) ([]string, error) {
+	return lint.ParseTests(b)
 }
 
 // decorateFlakeIssue extracts flake-related data from a GitHub issue adding it to
-// t.FlakeIssues[job].jobTestResults.Tests.LinkedBugs  map key'd by CI jobName
-func (rf *ReportedFlake) decorateFlakeIssue(i *github.Issue) error {
-	tgLink := tgUrl.FindString(*i.Body)
-	rf.Logger.Debugf("len(tgLin):%d", len(tgLink))
-	if len(tgLink) > 0 {
-		d, err := getCITargets(tgDashRE, tgLink)
-		if err != nil {
-			return errors.New("Error decorating issue " + err.Error())
-		}
-		j, err := getCITargets(tgJobRE, tgLink)
-		if err != nil {
-			return errors.New("Error decorating issue " + err.Error())
+// rf.Issues, the issue's card is in column of the project board
+func (rf *ReportedFlake) decorateFlakeIssue(i *github.Issue, column string) error {
+	links := lint.FindTestGridLinks(i.GetBody())
+	rf.Logger.Debugf("len(links):%d", len(links))
+	if len(links) > 0 {
+		d, j := links[0].Dashboard, links[0].Job
+		if j == "" {
+			return errors.New("Error decorating issue TestGrid link has no #job " + links[0].Url)
 		}
 
-		ta, err := rf.getReportedTests(*i.Body) // Getting tests from initial body for now may need to process comments aswel
-		if err == nil {
-			return errors.New("Error decorating issue " + strconv.FormatInt(*i.ID, 10))
+		ta, err := lint.ParseTests(i.GetBody()) // comments are parsed for Mentions by addMentions
+		if err != nil {
+			return errors.New("Error decorating issue " + strconv.FormatInt(i.GetID(), 10) + " " + err.Error())
 		}
 		rf.Logger.Debugf("Issue has mentioned these tests :%v", ta)
 		// Append this report to the list of flakes logged against this job
-		var tmp actualFlake
-		tmp.dashboard = d
-		tmp.job = j
-		tmp.ghIssue = i
-		tmp.tests = ta
-		if j != "" {
-			// TODO figure out how the class collaborate!
-			// pass in a ref to the cisignal summary object so we can do this lookup
-			job, exists := rf.CiStatus.FlakingJobs[j]
-			if exists {
-				for _, test := range job.JobTestResults.Tests {
-					test.LinkedBugs = make([]interface{}, len(ta))
-					for _, actualFlake := range ta {
-						if test.Name == actualFlake {
-							test.LinkedBugs = append(test.LinkedBugs, actualFlake)
-						}
-					}
-				}
-			}
-		}
-
+		rf.Issues = append(rf.Issues, FlakeIssue{
+			LinkedIssue: ci.LinkedIssue{
+				Number:    i.GetNumber(),
+				Repo:      repoFromUrl(i.GetRepositoryURL()),
+				Title:     i.GetTitle(),
+				URL:       i.GetHTMLURL(),
+				State:     i.GetState(),
+				Labels:    labelNames(i.Labels),
+				Assignees: userLogins(i.Assignees),
+				Column:    column,
+				CreatedAt: i.GetCreatedAt(),
+				UpdatedAt: i.GetUpdatedAt(),
+			},
+			Dashboard: d,
+			Job:       j,
+			Tests:     ta,
+		})
 	} else {
-		return errors.New("Could not find TestGridURL regExp in Issue" + *i.Title)
+		return errors.New("Could not find TestGrid link in Issue " + i.GetTitle())
 	}
 	return nil
 }
 
-// CollectIssuesFromBoard retrieves logged Flake Issues from the CI Signal Board
-// and adds them to the LinkedBugs[] on the jobTestResults
-func (rf *ReportedFlake) CollectIssuesFromBoard(cs *ci.CiStatus) {
+// CollectIssuesFromBoard retrieves logged Flake Issues from rf.Board, merging
+// issues already collected from other boards or searches, and links them to
+// the tests of the jobs in c they track, see LinkIssues. Issues that do not
+// follow the flake template are logged and skipped. Cards whose issue could not
+// be fetched do not stop the collection, they are returned together in a
+// CardErrors. Any other error means the board could not be read.
+func (rf *ReportedFlake) CollectIssuesFromBoard(c *ci.Collection) error {
+	rf.Collection = c
 
-	githubApiToken := os.Getenv("GITHUB_AUTH_TOKEN")
-	if githubApiToken == "" {
-		rf.Logger.Error("GITHUB_API_TOKEN is not set in process env.")
-		panic("Quitting")
-	}
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubApiToken})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-	rl, _, e := client.RateLimits(ctx)
-
-	if _, ok := e.(*github.RateLimitError); ok {
-		rf.Logger.Error(rl)
-		panic("Github client Rate Limit reached")
+	if rf.GitHub == nil {
+		client, err := NewClientFromEnv(ctx, &RateLimiter{Logger: rf.Logger})
+		if err != nil {
+			return err
+		}
+		rf.GitHub = client
 	}
+	gh := rf.GitHub
 
-	opt := &github.ProjectCardListOptions{}
-	listOpt := &github.ListOptions{}
-	cols, r, err := client.Projects.ListProjectColumns(ctx, ciSignalBoardId, listOpt)
-	rf.Logger.Infof("c.P.LPC cols %v\n", cols)
-
+	board := rf.Board
+	if board == nil {
+		board = &ClassicBoard{ID: CI_SIGNAL_BOARD_ID}
+	}
+	boardIssues, err := board.BoardIssues(ctx, gh)
 	if err != nil {
-		rf.Logger.Error(err)
-		rf.Logger.Error(r)
-		panic("Github client could not get")
+		return err
 	}
+	rf.Logger.Infof("Found %d issues on the project board", len(boardIssues))
 
-	for _, col := range cols {
-		cards, _, err := client.Projects.ListProjectCards(ctx, *col.ID, opt)
-
+	var errs CardErrors
+	fail := func(url, stage string, err error) {
+		rf.Logger.Errorf("Collecting %s failed at %s: %v", url, stage, err)
+		errs = append(errs, CardError{URL: url, Stage: stage, Err: err})
+	}
+	for _, bi := range boardIssues {
+		if known := rf.findIssue(bi.Owner+"/"+bi.Repo, bi.Number); known != nil {
+			known.Sources = append(known.Sources, bi.Source)
+			if known.Column == "" {
+				known.Column = bi.Column
+			}
+			continue
+		}
+		issue, err := rf.getIssueDetail(gh, bi)
+		if err != nil {
+			fail(bi.URL(), STAGE_GET_ISSUE, err)
+			continue
+		}
+		rf.Logger.Debugf("issueDetail is :%s", issue.GetTitle())
+		if err = rf.decorateFlakeIssue(issue, bi.Column); err != nil {
+			rf.Logger.Warnf("Skipping %s: %v", bi.URL(), err)
+			continue
+		}
+		flakeIssue := &rf.Issues[len(rf.Issues)-1]
+		flakeIssue.Sources = []string{bi.Source}
+		if err = rf.addMentions(gh, flakeIssue, issue.GetBody()); err != nil {
+			fail(flakeIssue.URL, STAGE_COMMENTS, err)
+		}
+		flakeIssue.PullRequests, err = rf.getLinkedPullRequests(gh, flakeIssue.Repo, flakeIssue.Number)
 		if err != nil {
-			rf.Logger.Error(err)
-			rf.Logger.Error(r)
-			panic("Github client could not get")
+			fail(flakeIssue.URL, STAGE_PULL_REQUESTS, err)
 		}
+	}
+	LinkIssues(c, rf.Issues)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-		for _, card := range cards {
-			contentUrl := card.GetContentURL()
-			if contentUrl != "" {
-				rf.Logger.Debugf("card url is :%s", contentUrl)
-				// colId := card.GetColumnID()
-				// if (colId == ciSignalNewCardColId) || (colId == ciSignalUnderInvestigationColId) || (colId == ciSignalObservingColId) {
-				issue, err := rf.getIssueDetail(client, contentUrl)
-				rf.Logger.Debugf("issueDetail is :%s", issue.GetTitle())
-				if err != nil {
-					rf.Logger.Errorf("getIssueDetail(%s) %v returned %v\n",
-						card.GetContentURL(), card, err)
-					break
+// LinkIssues sets the LinkedBugs of every test in c to the issues that track
+// it, replacing any linked before
+func LinkIssues(c *ci.Collection, issues []FlakeIssue) {
+	for _, cs := range c.Dashboards {
+		for _, jobs := range cs.Jobs {
+			for jobName, job := range jobs {
+				if job.JobTestResults == nil {
+					continue
 				}
-				err = rf.decorateFlakeIssue(issue)
-				if err != nil {
-					rf.Logger.Errorf("Error decorating Flake for this card %s, %s\nReason: %v",
-						issue.GetTitle(), issue.GetURL(), err)
-					break
+				tests := job.JobTestResults.Tests
+				for k := range tests {
+					tests[k].LinkedBugs = nil
+					for _, i := range issues {
+						if i.Tracks(jobName, tests[k].Name) {
+							tests[k].LinkedBugs = append(tests[k].LinkedBugs, i.LinkedIssue)
+						}
+					}
 				}
-				// } else {
-				// 	rf.Logger.Warnf("Ignoring card not new or under investigation",card.GetContentURL())			}
 			}
-
 		}
 	}
 }
 
-// getCITargets is a helper that returns first occurance of the group in re
-// and err if regexp does not match
-func getCITargets(re *regexp.Regexp, url string) (string, error) {
-	matches := re.FindStringSubmatch(url)
-	if len(matches) > 0 {
-		target := strings.TrimSuffix(matches[1], "\r")
-		return target, nil
-	} else {
-		errMsg := fmt.Sprintf("getCITargest RegEx %v not found in url:%s", re.String(), url)
-		err := errors.New(errMsg)
-		return "", err
+// userLogins returns the logins of users
+func userLogins(users []*github.User) []string {
+	var logins []string
+	for _, u := range users {
+		logins = append(logins, u.GetLogin())
 	}
+	return logins
 }
 
-// getReportedTests collects tests referenced in the body of a formatted Flake Issue on GitHub
-// Each non-empty line between "Which test(s) are flaking:" and Testgrid link:
-// is congetTestssidered to be a test
-func (rf *ReportedFlake) getReportedTests(b string) ([]string, error) {
-	var tests []string
-
-	start := strings.Index(b, FLAKE_TEMPLATE_HEADER_WTAF) + len(FLAKE_TEMPLATE_HEADER_WTAF)
-	end := strings.Index(b, FLAKE_TEMPLATE_HEADER_TGL)
-
-	if start == -1 {
-		return nil, errors.New("Missing header " + FLAKE_TEMPLATE_HEADER_WTAF)
+// labelNames returns the names of labels
+func labelNames(labels []github.Label) []string {
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.GetName())
 	}
+	return names
+}
 
-	if end == -1 {
-		return nil, errors.New("Missing header " + FLAKE_TEMPLATE_HEADER_TGL)
+// repoFromUrl returns owner/name from the API url of a GitHub repository
+func repoFromUrl(repositoryUrl string) string {
+	urlParts := strings.Split(strings.TrimSuffix(repositoryUrl, "/"), "/")
+	if len(urlParts) < 2 {
+		return repositoryUrl
 	}
+	return urlParts[len(urlParts)-2] + "/" + urlParts[len(urlParts)-1]
+}
 
-	// Start reading after "Which test(s) are flaking:"
-	r := strings.NewReader(b[start:end])
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		tests = append(tests, s.Text())
+// findIssue returns the issue number of repo (owner/name) if it has already
+// been collected, or nil
+func (rf *ReportedFlake) findIssue(repo string, number int) *FlakeIssue {
+	for i := range rf.Issues {
+		if rf.Issues[i].Repo == repo && rf.Issues[i].Number == number {
+			return &rf.Issues[i]
+		}
 	}
-	return tests, nil
+	return nil
 }
-// getIssueDetail
-func (rf *ReportedFlake) getIssueDetail(client *github.Client, jobSummaryUrl string) (*github.Issue, error) {
-	rf.Logger.Tracef("getIssueDetail %s\n", jobSummaryUrl)
-	if jobSummaryUrl == "" {
-		err := errors.New("jobSummary url is nil")
-		return nil, err
-	}
-	urlParts := strings.Split(jobSummaryUrl, "/")
-	if len(urlParts) < 3 {
-		err := errors.New(fmt.Sprintf("Error spliting url %s", jobSummaryUrl))
-		return nil, err
-	}
 
-	i := urlParts[len(urlParts)-1]
-	r := urlParts[len(urlParts)-3]
-	o := urlParts[len(urlParts)-4]
-
-	issueNumber, err := strconv.Atoi(i)
-	if err != nil {
-		return nil, err
+// getIssueDetail fetches the issue on a project board, unless its reader
+// already has
+func (rf *ReportedFlake) getIssueDetail(gh GitHub, bi BoardIssue) (*github.Issue, error) {
+	if bi.Issue != nil {
+		return bi.Issue, nil
 	}
-	ghIssue, _, err := client.Issues.Get(context.Background(), o, r, issueNumber)
-
+	rf.Logger.Tracef("getIssueDetail %s/%s#%d\n", bi.Owner, bi.Repo, bi.Number)
+	ghIssue, err := gh.GetIssue(context.Background(), bi.Owner, bi.Repo, bi.Number)
 	if err != nil {
 		return nil, err
 	}
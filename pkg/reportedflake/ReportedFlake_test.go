@@ -1,6 +1,12 @@
 package reportedflake
 
-import "testing"
+import (
+	"io/ioutil"
+	"testing"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	log "github.com/sirupsen/logrus"
+)
 
 const (
 	FR_TEST = ` Which test(s) are flaking:
@@ -24,3 +30,158 @@ func TestParseTest(t *testing.T) {
 		t.Errorf("Expected to find %d test(s) but found %d %v \n", expectedTestCount, len(tests), tests)
 	}
 }
+
+// Tests that issues are linked to the tests they track, replacing the issues
+// linked before
+func TestLinkIssues(t *testing.T) {
+	results := &ci.TestGridJobResult{Tests: []ci.TestResult{
+		{Name: "[sig-node] a test", LinkedBugs: []ci.LinkedIssue{{Number: 99}}},
+		{Name: "[sig-node] another test"},
+	}}
+	c := &ci.Collection{Dashboards: []*ci.CiStatus{{Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{
+		ci.FLAKY: {"a-job": {OverallStatus: ci.FLAKY, JobTestResults: results}},
+	}}}}
+	issues := []FlakeIssue{
+		{LinkedIssue: ci.LinkedIssue{Number: 1}, Job: "a-job", Tests: []string{"a test"}},
+		{LinkedIssue: ci.LinkedIssue{Number: 2}, Job: "a-job"},
+		{LinkedIssue: ci.LinkedIssue{Number: 3}, Job: "other-job"},
+	}
+
+	LinkIssues(c, issues)
+	bugs := results.Tests[0].LinkedBugs
+	if len(bugs) != 2 || bugs[0].Number != 1 || bugs[1].Number != 2 {
+		t.Errorf("Expected issues 1 and 2 linked to the first test, got %+v", bugs)
+	}
+	if bugs = results.Tests[1].LinkedBugs; len(bugs) != 1 || bugs[0].Number != 2 {
+		t.Errorf("Expected issue 2 linked to the second test, got %+v", bugs)
+	}
+}
+
+// Tests that the issues on the cards of a board are fetched, decorated with
+// their comments and pull requests and linked to the tests they track, that
+// cards whose issue can not be fetched are reported without stopping the
+// collection, that issues which do not follow the template are skipped
+// without being reported, and that an issue found again by a search is merged
+// with the one on the board
+func TestCollectIssuesFromBoard(t *testing.T) {
+	gh, done := fakeGitHub(t)
+	defer done()
+
+	jobs := map[string]ci.JobStatus{
+		"job-a": {OverallStatus: ci.FLAKY, JobTestResults: &ci.TestGridJobResult{Tests: []ci.TestResult{
+			{Name: "[sig-node] a test"}, {Name: "[sig-node] another"},
+		}}},
+		"job-b": {OverallStatus: ci.FLAKY, JobTestResults: &ci.TestGridJobResult{Tests: []ci.TestResult{
+			{Name: "[sig-apps] b test"},
+		}}},
+	}
+	c := &ci.Collection{Dashboards: []*ci.CiStatus{{
+		Name: "sig-release-master-blocking",
+		Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{ci.FLAKY: jobs},
+	}}}
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	rf := &ReportedFlake{Logger: logger, GitHub: gh, Board: &ClassicBoard{ID: 1}}
+	err := rf.CollectIssuesFromBoard(c)
+
+	// the note is skipped, issue 2, which has no TestGrid link, and issue 5,
+	// which is not found, are not collected, and only issue 5 is reported
+	errs, ok := err.(CardErrors)
+	if !ok || len(errs) != 1 ||
+		errs[0].URL != "https://github.com/kubernetes/kubernetes/issues/5" || errs[0].Stage != STAGE_GET_ISSUE {
+		t.Errorf("Expected issue 5 to be reported, got %v", err)
+	}
+	if len(rf.Issues) != 2 || rf.Issues[0].Number != 1 || rf.Issues[1].Number != 3 {
+		t.Fatalf("Expected issues 1 and 3, got %+v", rf.Issues)
+	}
+	i := rf.Issues[0]
+	if i.Repo != "kubernetes/kubernetes" || i.Column != "New (no response yet)" || i.Job != "job-a" ||
+		len(i.Tests) != 1 || i.Tests[0] != "a test" || len(i.Labels) != 2 || len(i.Assignees) != 1 ||
+		i.CreatedAt.IsZero() || len(i.Sources) != 1 || i.Sources[0] != "board:1" {
+		t.Errorf("Unexpected decoration of issue 1 %+v", i)
+	}
+	if len(i.Mentions) != 2 || i.Mentions[1].Job != "job-b" || len(i.Mentions[1].Tests) != 1 {
+		t.Errorf("Expected issue 1 to mention job-b in a comment, got %+v", i.Mentions)
+	}
+	if len(i.PullRequests) != 1 || i.PullRequests[0].Number != 10 || i.Effort() != EFFORT_PR_SUBMITTED {
+		t.Errorf("Expected pull request 10 on issue 1, got %+v", i.PullRequests)
+	}
+	if rf.Issues[1].Column != "Under investigation" || len(rf.Issues[1].Tests) != 0 {
+		t.Errorf("Unexpected decoration of issue 3 %+v", rf.Issues[1])
+	}
+
+	linked := func(job string, test int) []ci.LinkedIssue {
+		return jobs[job].JobTestResults.Tests[test].LinkedBugs
+	}
+	if bugs := linked("job-a", 0); len(bugs) != 1 || bugs[0].Number != 1 || len(bugs[0].Sources) != 1 {
+		t.Errorf("Expected a test to be linked to issue 1 found on the board, got %+v", bugs)
+	}
+	if bugs := linked("job-a", 1); len(bugs) != 0 {
+		t.Errorf("Expected another to be untracked, got %+v", bugs)
+	}
+	if bugs := linked("job-b", 0); len(bugs) != 2 {
+		t.Errorf("Expected b test to be linked to issues 1 and 3, got %+v", bugs)
+	}
+
+	rf.Board = &IssueSearch{Query: DEFAULT_FLAKE_SEARCH}
+	if err = rf.CollectIssuesFromBoard(c); err != nil {
+		t.Errorf("Expected the search to be collected, got %v", err)
+	}
+	if len(rf.Issues) != 2 || len(rf.Issues[0].Sources) != 2 || rf.Issues[0].Sources[1] != rf.Board.(*IssueSearch).Source() {
+		t.Errorf("Expected the issue found by search to be merged, got %+v", rf.Issues)
+	}
+	if bugs := linked("job-a", 0); len(bugs) != 1 || len(bugs[0].Sources) != 2 {
+		t.Errorf("Expected the linked issue to record the board and the search, got %+v", bugs)
+	}
+}
+
+// Tests that a board that can not be read is returned as an error rather than
+// a CardErrors
+func TestCollectIssuesFromMissingBoard(t *testing.T) {
+	gh, done := fakeGitHub(t)
+	defer done()
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	rf := &ReportedFlake{Logger: logger, GitHub: gh, Board: &ClassicBoard{ID: 2}}
+	err := rf.CollectIssuesFromBoard(&ci.Collection{})
+	if _, partial := err.(CardErrors); err == nil || partial {
+		t.Errorf("Expected an error reading the board, got %v", err)
+	}
+}
+
+// Tests that the issues on a projects-v2 board are collected and linked like
+// those on a classic board, in the column of their status field
+func TestCollectIssuesFromProjectV2Board(t *testing.T) {
+	gh, done := fakeGitHub(t)
+	defer done()
+
+	jobs := map[string]ci.JobStatus{
+		"job-a": {OverallStatus: ci.FLAKY, JobTestResults: &ci.TestGridJobResult{Tests: []ci.TestResult{
+			{Name: "[sig-node] a test"},
+		}}},
+	}
+	c := &ci.Collection{Dashboards: []*ci.CiStatus{{
+		Name: "sig-release-master-blocking",
+		Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{ci.FLAKY: jobs},
+	}}}
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	board := &ProjectV2Board{Owner: "kubernetes", Number: 68}
+	rf := &ReportedFlake{Logger: logger, GitHub: gh, Board: board}
+	if err := rf.CollectIssuesFromBoard(c); err != nil {
+		t.Fatalf("CollectIssuesFromBoard returned %v", err)
+	}
+
+	if len(rf.Issues) != 2 || rf.Issues[0].Number != 1 || rf.Issues[1].Number != 3 {
+		t.Fatalf("Expected issues 1 and 3, got %+v", rf.Issues)
+	}
+	if i := rf.Issues[0]; i.Column != "In Progress" || len(i.Sources) != 1 || i.Sources[0] != board.Source() {
+		t.Errorf("Unexpected column or sources of issue 1 %+v", i.LinkedIssue)
+	}
+	if bugs := jobs["job-a"].JobTestResults.Tests[0].LinkedBugs; len(bugs) != 1 || bugs[0].Number != 1 {
+		t.Errorf("Expected a test to be linked to issue 1, got %+v", bugs)
+	}
+}
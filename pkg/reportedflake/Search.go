@@ -0,0 +1,46 @@
+package reportedflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const DEFAULT_FLAKE_SEARCH string = "repo:kubernetes/kubernetes label:kind/flake is:open"
+
+// IssueSearch finds flake issues with a GitHub issue search query, e.g.
+// DEFAULT_FLAKE_SEARCH, paging through every result
+type IssueSearch struct {
+	Query string
+}
+
+func (s *IssueSearch) BoardIssues(ctx context.Context, gh GitHub) ([]BoardIssue, error) {
+	found, err := gh.SearchIssues(ctx, s.Query)
+	if err != nil {
+		return nil, fmt.Errorf("Searching for %q %w", s.Query, err)
+	}
+	var issues []BoardIssue
+	for i := range found {
+		issue := &found[i]
+		if issue.IsPullRequest() {
+			continue
+		}
+		ownerRepo := strings.Split(repoFromUrl(issue.GetRepositoryURL()), "/")
+		if len(ownerRepo) != 2 {
+			return nil, fmt.Errorf("Error splitting repository url %s", issue.GetRepositoryURL())
+		}
+		issues = append(issues, BoardIssue{
+			Owner:  ownerRepo[0],
+			Repo:   ownerRepo[1],
+			Number: issue.GetNumber(),
+			Source: s.Source(),
+			Issue:  issue,
+		})
+	}
+	return issues, nil
+}
+
+// Source names the search as the source of the issues it finds
+func (s *IssueSearch) Source() string {
+	return SOURCE_SEARCH + ":" + s.Query
+}
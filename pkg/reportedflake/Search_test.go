@@ -0,0 +1,47 @@
+package reportedflake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Tests that a search pages through its results, skipping pull requests and
+// keeping the issues it found so they need not be fetched again
+func TestIssueSearch(t *testing.T) {
+	var srvUrl string
+	client, done := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/issues" || r.URL.Query().Get("q") != DEFAULT_FLAKE_SEARCH {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%ssearch/issues?page=2>; rel="next"`, srvUrl))
+			w.Write([]byte(`{"total_count": 3, "items": [
+				{"number": 1, "repository_url": "https://api.github.com/repos/kubernetes/kubernetes"},
+				{"number": 2, "repository_url": "https://api.github.com/repos/kubernetes/kubernetes", "pull_request": {}}
+			]}`))
+			return
+		}
+		w.Write([]byte(`{"total_count": 3, "items": [
+			{"number": 3, "repository_url": "https://api.github.com/repos/kubernetes/kubernetes"}
+		]}`))
+	}))
+	defer done()
+	srvUrl = client.BaseURL.String()
+
+	s := &IssueSearch{Query: DEFAULT_FLAKE_SEARCH}
+	issues, err := s.BoardIssues(context.Background(), client)
+	if err != nil {
+		t.Fatalf("BoardIssues returned %v", err)
+	}
+	if len(issues) != 2 || issues[0].Number != 1 || issues[1].Number != 3 {
+		t.Fatalf("Expected issues 1 and 3, got %+v", issues)
+	}
+	for _, i := range issues {
+		if i.Owner != "kubernetes" || i.Repo != "kubernetes" || i.Source != SOURCE_SEARCH+":"+DEFAULT_FLAKE_SEARCH || i.Issue == nil {
+			t.Errorf("Unexpected issue %+v", i)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+// Serves the latest collected CI status as an HTML page and a JSON API,
+// re-collecting it on a schedule
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_INTERVAL time.Duration = time.Hour
+	API_STATUS_PATH  string        = "/api/status"
+)
+
+var page = template.Must(template.New("page").Funcs(template.FuncMap{
+	"percent": func(rate float64) float64 { return rate * 100 },
+}).Parse(pageTemplate))
+
+// Server keeps the most recent snapshot returned by Collect in memory
+type Server struct {
+	Collect  func() (*snapshot.Snapshot, error)
+	Interval time.Duration // DEFAULT_INTERVAL when zero
+	Logger   *log.Logger
+
+	mu      sync.RWMutex
+	latest  *View
+	lastErr error
+}
+
+// ListenAndServe collects CI status every s.Interval and serves the latest
+// collection on addr until the listener fails
+func (s *Server) ListenAndServe(addr string) error {
+	go s.schedule()
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+// Handler serves the HTML report on / and the same data as JSON on
+// API_STATUS_PATH
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveHTML)
+	mux.HandleFunc(API_STATUS_PATH, s.serveJSON)
+	return mux
+}
+
+// Refresh runs Collect once and makes the result the latest collection. A
+// panic in Collect is returned as an error so that the server keeps running.
+func (s *Server) Refresh() (err error) {
+	var snap *snapshot.Snapshot
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Collection panicked: %v", r)
+			s.mu.Lock()
+			s.lastErr = err
+			s.mu.Unlock()
+		}
+	}()
+
+	snap, err = s.Collect()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	if err != nil {
+		return err
+	}
+	s.latest = NewView(snap)
+	return nil
+}
+
+// schedule calls Refresh now and then every s.Interval
+func (s *Server) schedule() {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+	for {
+		if err := s.Refresh(); err != nil {
+			s.Logger.Error("Collecting CI status ", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// current returns the latest view, nil until the first collection completes
+func (s *Server) current() (*View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest, s.lastErr
+}
+
+func (s *Server) serveHTML(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	view, err := s.current()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct {
+		View  *View
+		Error error
+	}{view, err}
+	if err := page.Execute(w, data); err != nil {
+		s.Logger.Error("Rendering page ", err)
+	}
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request) {
+	view, _ := s.current()
+	if view == nil {
+		http.Error(w, "CI status has not been collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		s.Logger.Error("Encoding status ", err)
+	}
+}
+
+// View is a snapshot grouped by dashboard, status and SIG for display
+type View struct {
+	CollectedAt time.Time       `json:"collected_at"`
+	Dashboards  []DashboardView `json:"dashboards"`
+}
+
+type DashboardView struct {
+	Name     string       `json:"name"`
+	Url      string       `json:"url"`
+	Statuses []StatusView `json:"statuses"`
+}
+
+// StatusView lists the jobs with Status, the tests of flaking and failing
+// jobs are grouped by SIG
+type StatusView struct {
+	Status ci.OverallStatus `json:"status"`
+	Jobs   []JobView        `json:"jobs"`
+	Sigs   []SigView        `json:"sigs,omitempty"`
+}
+
+type JobView struct {
+	Name       string `json:"name"`
+	Url        string `json:"url"`
+	FetchError string `json:"fetch_error,omitempty"`
+}
+
+type SigView struct {
+	Sig   string     `json:"sig"`
+	Tests []TestView `json:"tests"`
+}
+
+type TestView struct {
+	Job    string          `json:"job"`
+	JobUrl string          `json:"job_url"`
+	Name   string          `json:"name"`
+	Stats  ci.TestStats    `json:"stats"`
+	Issues []rf.FlakeIssue `json:"issues,omitempty"`
+}
+
+// NewView groups the jobs and tests in snap for display
+func NewView(snap *snapshot.Snapshot) *View {
+	v := &View{CollectedAt: snap.CollectedAt}
+	for _, cs := range snap.Collection.Dashboards {
+		d := DashboardView{Name: cs.Name, Url: ci.SummaryUrl(cs.Name)}
+		for _, status := range cs.Statuses() {
+			d.Statuses = append(d.Statuses, newStatusView(cs, status, snap.Issues))
+		}
+		v.Dashboards = append(v.Dashboards, d)
+	}
+	return v
+}
+
+// newStatusView lists the jobs on cs with status, grouping their tests by SIG
+func newStatusView(cs *ci.CiStatus, status ci.OverallStatus, issues []rf.FlakeIssue) StatusView {
+	sv := StatusView{Status: status}
+	bySig := make(map[string][]TestView)
+
+	jobs := cs.Jobs[status]
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		job := jobs[name]
+		jobUrl := ci.DashboardJobUrl(cs.Name, name)
+		sv.Jobs = append(sv.Jobs, JobView{Name: name, Url: jobUrl, FetchError: job.FetchError})
+		if job.JobTestResults == nil {
+			continue
+		}
+		for _, test := range job.JobTestResults.Tests {
+			bySig[test.Sig] = append(bySig[test.Sig], TestView{
+				Job:    name,
+				JobUrl: jobUrl,
+				Name:   test.Name,
+				Stats:  test.Stats,
+				Issues: issuesFor(issues, name, test.Name),
+			})
+		}
+	}
+
+	sigs := make([]string, 0, len(bySig))
+	for sig := range bySig {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		sv.Sigs = append(sv.Sigs, SigView{Sig: sig, Tests: bySig[sig]})
+	}
+	return sv
+}
+
+// issuesFor returns the issues that report test flaking on job
+func issuesFor(issues []rf.FlakeIssue, job, test string) []rf.FlakeIssue {
+	var found []rf.FlakeIssue
+	for _, i := range issues {
+		if i.Tracks(job, test) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
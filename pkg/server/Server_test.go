@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+	"github.com/RobertKielty/flake-tracker/pkg/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+func testSnapshot() (*snapshot.Snapshot, error) {
+	flaky := &ci.TestGridJobResult{Tests: []ci.TestResult{
+		{Name: "[sig-node] a test", Sig: "[sig-node] ", Stats: ci.TestStats{Runs: 4, FlakeRate: 0.25}},
+	}}
+	cs := &ci.CiStatus{Name: "sig-release-master-blocking", Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{
+		ci.FLAKY:   {"flaky-job": {OverallStatus: ci.FLAKY, JobTestResults: flaky}},
+		ci.PASSING: {"passing-job": {OverallStatus: ci.PASSING}},
+	}}
+	c := &ci.Collection{CollectedAt: time.Now(), Dashboards: []*ci.CiStatus{cs}}
+	issues := []rf.FlakeIssue{{
+		LinkedIssue: ci.LinkedIssue{Number: 42, URL: "https://github.com/kubernetes/kubernetes/issues/42"},
+		Job:         "flaky-job",
+		Tests:       []string{"[sig-node] a test"},
+	}}
+	return snapshot.New(c, issues), nil
+}
+
+// Tests that the latest collection is served as HTML and JSON
+func TestServer(t *testing.T) {
+	s := &Server{Collect: testSnapshot, Logger: log.New()}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + API_STATUS_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d before first collection, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("Refresh returned %v", err)
+	}
+
+	resp, err = http.Get(ts.URL + API_STATUS_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v View
+	err = json.NewDecoder(resp.Body).Decode(&v)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Decoding status: %v", err)
+	}
+	flaky := v.Dashboards[0].Statuses[0]
+	if flaky.Status != ci.FLAKY || len(flaky.Sigs) != 1 || len(flaky.Sigs[0].Tests[0].Issues) != 1 {
+		t.Errorf("Unexpected flaky status view %+v", flaky)
+	}
+
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(body)
+	for _, expected := range []string{
+		"https://testgrid.k8s.io/sig-release-master-blocking#flaky-job",
+		"https://github.com/kubernetes/kubernetes/issues/42",
+		"25.0%",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected page to contain %s\n%s", expected, html)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+const pageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Flake Tracker</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+.FAILING { color: #c00; } .FLAKY { color: #a60; } .PASSING { color: #080; }
+</style>
+</head>
+<body>
+<h1>Flake Tracker</h1>
+{{if .Error}}<p class="FAILING">Last collection failed: {{.Error}}</p>{{end}}
+{{with .View}}
+<p>Collected at {{.CollectedAt.Format "Mon Jan _2 15:04:05 MST 2006"}} &middot; <a href="` + API_STATUS_PATH + `">JSON</a></p>
+{{range .Dashboards}}
+<h2><a href="{{.Url}}">{{.Name}}</a></h2>
+{{range .Statuses}}
+<h3 class="{{.Status}}">{{.Status}} ({{len .Jobs}})</h3>
+{{if .Sigs}}
+{{range .Sigs}}
+<h4>{{if .Sig}}{{.Sig}}{{else}}job-owner{{end}}</h4>
+<table>
+<tr><th>Job</th><th>Test</th><th>Flake rate</th><th>Failure rate</th><th>Runs</th><th>Issues</th></tr>
+{{range .Tests}}
+<tr>
+<td><a href="{{.JobUrl}}">{{.Job}}</a></td>
+<td>{{.Name}}</td>
+<td>{{printf "%2.1f%%" (percent .Stats.FlakeRate)}}</td>
+<td>{{printf "%2.1f%%" (percent .Stats.FailureRate)}}</td>
+<td>{{.Stats.Runs}}</td>
+<td>{{range .Issues}}<a href="{{.URL}}">#{{.Number}}</a> ({{.Effort}}) {{else}}<em>untracked</em>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+<ul>
+{{range .Jobs}}<li><a href="{{.Url}}">{{.Name}}</a>{{if .FetchError}} &mdash; tests unknown: {{.FetchError}}{{end}}</li>
+{{end}}
+</ul>
+{{end}}
+{{end}}
+{{else}}
+{{if not .Error}}<p>CI status has not been collected yet.</p>{{end}}
+{{end}}
+</body>
+</html>
+`
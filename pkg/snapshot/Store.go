@@ -0,0 +1,164 @@
+package snapshot
+
+// Stores each collection of CI status as a JSON file in a data directory so
+// that flakes can be compared from one run to the next
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+const (
+	// SNAPSHOT_VERSION is incremented whenever the snapshot format changes in
+	// a way that older versions of the tracker can not read
+	SNAPSHOT_VERSION int    = 1
+	SNAPSHOT_ID_FMT  string = "20060102T150405Z"
+	snapshotExt      string = ".json"
+)
+
+// Snapshot is everything collected in a single run of the collector
+type Snapshot struct {
+	Version     int
+	CollectedAt time.Time
+	Collection  *ci.Collection
+	Issues      []rf.FlakeIssue
+}
+
+// Store keeps snapshots in Dir, one JSON file per snapshot named by its ID
+type Store struct {
+	Dir string
+}
+
+// ErrNoSnapshots is returned by Latest when the store is empty
+var ErrNoSnapshots = errors.New("No snapshots in store")
+
+// New returns a Snapshot of the collection c and the issues linked to it
+func New(c *ci.Collection, issues []rf.FlakeIssue) *Snapshot {
+	return &Snapshot{
+		Version:     SNAPSHOT_VERSION,
+		CollectedAt: c.CollectedAt,
+		Collection:  c,
+		Issues:      issues,
+	}
+}
+
+// ID identifies the snapshot by the UTC time it was collected at, IDs sort in
+// the order the snapshots were collected
+func (s *Snapshot) ID() string {
+	return s.CollectedAt.UTC().Format(SNAPSHOT_ID_FMT)
+}
+
+// Save writes snap to the store, replacing any snapshot with the same ID
+func (st *Store) Save(snap *Snapshot) (string, error) {
+	if err := os.MkdirAll(st.Dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so a failed run never leaves a
+	// truncated snapshot behind
+	tmp, err := ioutil.TempFile(st.Dir, ".snapshot-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	id := snap.ID()
+	return id, os.Rename(tmp.Name(), st.path(id))
+}
+
+// List returns the IDs of the snapshots in the store, oldest first
+func (st *Store) List() ([]string, error) {
+	files, err := ioutil.ReadDir(st.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != snapshotExt {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, snapshotExt))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Load reads the snapshot with the given ID from the store
+func (st *Store) Load(id string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(st.path(id))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("Unmarshalling snapshot %s: %w", id, err)
+	}
+	if snap.Version > SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("Snapshot %s has version %d, this version of the tracker reads up to %d",
+			id, snap.Version, SNAPSHOT_VERSION)
+	}
+	return &snap, nil
+}
+
+// Latest loads the most recently collected snapshot in the store
+func (st *Store) Latest() (*Snapshot, error) {
+	ids, err := st.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoSnapshots
+	}
+	return st.Load(ids[len(ids)-1])
+}
+
+// Prune removes snapshots collected before the given time, returning the IDs
+// of the snapshots removed
+func (st *Store) Prune(before time.Time) ([]string, error) {
+	ids, err := st.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, id := range ids {
+		collectedAt, err := time.Parse(SNAPSHOT_ID_FMT, id)
+		if err != nil || !collectedAt.Before(before) {
+			continue
+		}
+		if err = os.Remove(st.path(id)); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, id)
+	}
+	return pruned, nil
+}
+
+func (st *Store) path(id string) string {
+	return filepath.Join(st.Dir, id+snapshotExt)
+}
@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	ci "github.com/RobertKielty/flake-tracker/pkg/cistatus"
+	rf "github.com/RobertKielty/flake-tracker/pkg/reportedflake"
+)
+
+// Tests saving, listing, loading and pruning snapshots in a store
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st := &Store{Dir: dir}
+
+	lastWeek := time.Date(2020, time.October, 1, 9, 0, 0, 0, time.UTC)
+	thisWeek := lastWeek.Add(7 * 24 * time.Hour)
+	for _, at := range []time.Time{thisWeek, lastWeek} {
+		c := &ci.Collection{CollectedAt: at, Dashboards: []*ci.CiStatus{{
+			Name: "sig-release-master-blocking",
+			Jobs: map[ci.OverallStatus]map[string]ci.JobStatus{
+				ci.FLAKY: {"a-job": {OverallStatus: ci.FLAKY}},
+			},
+		}}}
+		issues := []rf.FlakeIssue{{LinkedIssue: ci.LinkedIssue{Number: 1}, Job: "a-job", Tests: []string{"a-test"}}}
+		if _, err := st.Save(New(c, issues)); err != nil {
+			t.Fatalf("Save returned %v", err)
+		}
+	}
+
+	ids, err := st.List()
+	if err != nil || len(ids) != 2 || ids[0] != "20201001T090000Z" {
+		t.Fatalf("Expected two snapshots oldest first, got %v %v", ids, err)
+	}
+
+	latest, err := st.Latest()
+	if err != nil {
+		t.Fatalf("Latest returned %v", err)
+	}
+	if !latest.CollectedAt.Equal(thisWeek) || latest.Version != SNAPSHOT_VERSION {
+		t.Errorf("Expected snapshot from %v, got %v version %d", thisWeek, latest.CollectedAt, latest.Version)
+	}
+	job := latest.Collection.Dashboards[0].Jobs[ci.FLAKY]["a-job"]
+	if job.OverallStatus != ci.FLAKY || len(latest.Issues) != 1 || latest.Issues[0].Job != "a-job" {
+		t.Errorf("Snapshot did not round trip %+v", latest)
+	}
+
+	pruned, err := st.Prune(thisWeek)
+	if err != nil || len(pruned) != 1 || pruned[0] != ids[0] {
+		t.Errorf("Expected %s to be pruned, got %v %v", ids[0], pruned, err)
+	}
+	if ids, _ = st.List(); len(ids) != 1 {
+		t.Errorf("Expected one snapshot after pruning, got %v", ids)
+	}
+}